@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"context"
+	"mcp/server/config"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+type Caller struct {
+	Name string
+	Role string
+}
+
+type callerKey struct{}
+
+// anonymous 未携带或携带了未知 token 的调用方
+var anonymous = &Caller{Name: "anonymous"}
+
+// HTTPContextFunc 从 HTTP 请求头中识别调用方，并写入 context，供 StreamableHTTPServer 使用
+func HTTPContextFunc(ctx context.Context, r *http.Request) context.Context {
+	token := strings.TrimSpace(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+	if token == "" {
+		token = strings.TrimSpace(r.Header.Get("X-Api-Key"))
+	}
+
+	return context.WithValue(ctx, callerKey{}, lookupCaller(token))
+}
+
+func lookupCaller(token string) *Caller {
+	if token == "" || config.Cfg.Auth == nil {
+		return anonymous
+	}
+
+	for _, c := range config.Cfg.Auth.Callers {
+		if c.Token != "" && c.Token == token {
+			return &Caller{Name: c.Name, Role: c.Role}
+		}
+	}
+
+	return anonymous
+}
+
+// CallerFromContext 返回当前请求的调用方，未识别时返回 anonymous
+func CallerFromContext(ctx context.Context) *Caller {
+	if c, ok := ctx.Value(callerKey{}).(*Caller); ok {
+		return c
+	}
+
+	return anonymous
+}
+
+// CanUnmask 判断调用方是否拥有查看未脱敏个人信息的角色
+func CanUnmask(ctx context.Context) bool {
+	caller := CallerFromContext(ctx)
+	if caller.Role == "" || config.Cfg.Privacy == nil {
+		return false
+	}
+
+	return slices.Contains(config.Cfg.Privacy.UnmaskRoles, caller.Role)
+}
+
+// CanGrant 判断调用方是否拥有发放栏目权益的角色
+func CanGrant(ctx context.Context) bool {
+	caller := CallerFromContext(ctx)
+	if caller.Role == "" || config.Cfg.Benefit == nil {
+		return false
+	}
+
+	return slices.Contains(config.Cfg.Benefit.GrantRoles, caller.Role)
+}
+
+// CanViewRetention 判断调用方是否拥有查看 generated/ 清理计划的角色
+func CanViewRetention(ctx context.Context) bool {
+	caller := CallerFromContext(ctx)
+	if caller.Role == "" || config.Cfg.Retention == nil {
+		return false
+	}
+
+	return slices.Contains(config.Cfg.Retention.ReportRoles, caller.Role)
+}
@@ -13,6 +13,7 @@ import (
 	"log"
 	"mcp/server/config"
 	"os"
+	"time"
 )
 
 var (
@@ -29,7 +30,8 @@ func InitMysql(cfg *config.MysqlConfig) {
 	}
 
 	// 构建 DSN 连接字符串
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True",
+	// 时间统一按 UTC 存取，user_stats 等按北京时间分桶的统计依赖这一点
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=UTC",
 		cfg.UserName,
 		cfg.Password,
 		cfg.Host,
@@ -98,22 +100,34 @@ func InitMinIO(cfg *config.MinIO) {
 	if err != nil {
 		log.Fatalln("MinIO 连接失败:", err)
 	}
+	MinIO = minioClient
 
-	// 自动创建 Bucket (如果不存在)
-	ctx := context.Background()
-	exists, err := minioClient.BucketExists(ctx, cfg.BucketName)
+	// 自动创建 Bucket (如果不存在)；MinIO 暂时不可用时不阻止启动，在后台重试
+	go func() {
+		for {
+			err := ensureBucket(context.Background(), minioClient, cfg.BucketName)
+			if err == nil {
+				return
+			}
+			log.Println("检查 Bucket 失败，30 秒后重试:", err)
+			time.Sleep(30 * time.Second)
+		}
+	}()
+}
+
+func ensureBucket(ctx context.Context, minioClient *minio.Client, bucket string) error {
+	exists, err := minioClient.BucketExists(ctx, bucket)
 	if err != nil {
-		log.Fatalln("检查 Bucket 失败:", err)
+		return err
 	}
 	if !exists {
-		err = minioClient.MakeBucket(ctx, cfg.BucketName, minio.MakeBucketOptions{})
-		if err != nil {
-			log.Fatalln("创建 Bucket 失败:", err)
+		if err := minioClient.MakeBucket(ctx, bucket, minio.MakeBucketOptions{}); err != nil {
+			return err
 		}
-		log.Printf("Bucket '%s' 创建成功\n", cfg.BucketName)
+		log.Printf("Bucket '%s' 创建成功\n", bucket)
 	}
 
-	MinIO = minioClient
+	return nil
 }
 
 func Close() {
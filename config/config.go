@@ -34,12 +34,114 @@ type MinIO struct {
 	BucketName      string `yaml:"bucketName"`
 }
 
+type StorageConfig struct {
+	// 对象存储后端：minio（默认）、local（本地目录）、memory（内存，重启后丢失）
+	Backend string `yaml:"backend"`
+	// local 后端保存文件的目录，默认 data/objects
+	Dir string `yaml:"dir"`
+	// local、memory 后端的下载链接由本服务提供，这里填写客户端能访问到的服务地址
+	PublicURL string `yaml:"publicURL"`
+	// 下载链接的签名密钥，为空时每次启动随机生成
+	SigningKey string `yaml:"signingKey"`
+}
+
+type CallerConfig struct {
+	Name  string `yaml:"name"`
+	Token string `yaml:"token"`
+	Role  string `yaml:"role"`
+}
+
+type AuthConfig struct {
+	// 调用方通过 Authorization: Bearer <token> 或 X-Api-Key 头标识身份
+	Callers []CallerConfig `yaml:"callers"`
+}
+
+type PrivacyConfig struct {
+	// 拥有以下角色的调用方可以看到未脱敏的手机号、邮箱、真实姓名、用户名和昵称
+	UnmaskRoles []string `yaml:"unmaskRoles"`
+}
+
+type BenefitConfig struct {
+	// 拥有以下角色的调用方才能通过 grant_subject_benefit 发放栏目权益，dry_run 预览不受限制
+	GrantRoles []string `yaml:"grantRoles"`
+}
+
+type SubjectConfig struct {
+	Id      int      `yaml:"id"`
+	Name    string   `yaml:"name"`
+	Aliases []string `yaml:"aliases"`
+}
+
+type CatalogConfig struct {
+	Subjects []SubjectConfig `yaml:"subjects"`
+	// 不为空时，额外从该表读取 id、title 两列合并到栏目目录中
+	SubjectTable string `yaml:"subjectTable"`
+}
+
+type UploadConfig struct {
+	MaxSizeMB           int      `yaml:"maxSizeMB"`
+	ExpiryMinutes       int      `yaml:"expiryMinutes"`
+	AllowedContentTypes []string `yaml:"allowedContentTypes"`
+}
+
+type ReportParamConfig struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	Required    bool   `yaml:"required"`
+	Default     string `yaml:"default"`
+}
+
+type ReportTemplateConfig struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	// 输出格式：markdown、html、pdf、csv、xlsx；csv、xlsx 模板需要输出 CSV 文本或 JSON 数组
+	Format string `yaml:"format"`
+	// 模板内容二选一：Template 直接写在配置中，Object 为模板文件在 MinIO 中的对象名，每次渲染时重新读取
+	Template string              `yaml:"template"`
+	Object   string              `yaml:"object"`
+	Params   []ReportParamConfig `yaml:"params"`
+}
+
+type ReportConfig struct {
+	// 生成 PDF 时嵌入的中文字体，必须是 TTF 格式（不支持 TTC/OTF），例如 NotoSansSC-Regular.ttf
+	FontPath     string                 `yaml:"fontPath"`
+	BoldFontPath string                 `yaml:"boldFontPath"`
+	Templates    []ReportTemplateConfig `yaml:"templates"`
+}
+
+type DocumentConfig struct {
+	// 生成文件下载链接的默认有效期，默认 60 分钟
+	LinkExpiryMinutes int `yaml:"linkExpiryMinutes"`
+	// 调用方可以指定的最长有效期，不超过预签名链接的上限 7 天
+	MaxLinkExpiryMinutes int `yaml:"maxLinkExpiryMinutes"`
+}
+
+type RetentionConfig struct {
+	// generated/ 下文件的保留天数，0 表示不按时间清理
+	MaxAgeDays int `yaml:"maxAgeDays"`
+	// generated/ 下文件的总大小上限，超出时从最早的文件开始删除，0 表示不限制
+	MaxTotalSizeMB int `yaml:"maxTotalSizeMB"`
+	// 后台清理的间隔，默认 60 分钟
+	SweepIntervalMinutes int `yaml:"sweepIntervalMinutes"`
+	// 拥有以下角色的调用方才能通过 retention_report 查看清理计划，计划中包含所有调用方生成的文件
+	ReportRoles []string `yaml:"reportRoles"`
+}
+
 type Config struct {
-	Qdrant   *QdrantConfig   `yaml:"qdrant"`
-	OpenAI   *OpenAIConfig   `yaml:"openAI"`
-	Temporal *TemporalConfig `yaml:"temporal"`
-	Mysql    *MysqlConfig    `yaml:"mysql"`
-	MinIO    *MinIO          `yaml:"minIO"`
+	Qdrant    *QdrantConfig    `yaml:"qdrant"`
+	OpenAI    *OpenAIConfig    `yaml:"openAI"`
+	Temporal  *TemporalConfig  `yaml:"temporal"`
+	Mysql     *MysqlConfig     `yaml:"mysql"`
+	MinIO     *MinIO           `yaml:"minIO"`
+	Storage   *StorageConfig   `yaml:"storage"`
+	Auth      *AuthConfig      `yaml:"auth"`
+	Privacy   *PrivacyConfig   `yaml:"privacy"`
+	Benefit   *BenefitConfig   `yaml:"benefit"`
+	Catalog   *CatalogConfig   `yaml:"catalog"`
+	Upload    *UploadConfig    `yaml:"upload"`
+	Report    *ReportConfig    `yaml:"report"`
+	Document  *DocumentConfig  `yaml:"document"`
+	Retention *RetentionConfig `yaml:"retention"`
 }
 
 var (
@@ -5,12 +5,12 @@ import (
 )
 
 type ActivityFreeSubject struct {
-	ID              int64 `gorm:"primarykey"`
-	UserId          int
-	SubjectId       int
-	SubjectFreeDays int
-	CreatedAt       time.Time
-	UpdatedAt       time.Time
+	ID              int64     `gorm:"primarykey" desc:"记录 ID"`
+	UserId          int       `desc:"用户 ID" query:"filter"`
+	SubjectId       int       `desc:"栏目 ID，来自 subject://catalog" query:"filter"`
+	SubjectFreeDays int       `desc:"免费天数，到期时间 = 领取时间 + 免费天数"`
+	CreatedAt       time.Time `desc:"领取时间"`
+	UpdatedAt       time.Time `desc:"更新时间"`
 }
 
 func (a *ActivityFreeSubject) TableName() string {
@@ -0,0 +1,23 @@
+package dao
+
+import (
+	"time"
+)
+
+// BenefitGrantAudit 记录通过 grant_subject_benefit 工具写入的每一条栏目权益
+type BenefitGrantAudit struct {
+	ID              int64  `gorm:"primarykey"`
+	BatchId         string `gorm:"size:64;index"`
+	Operator        string `gorm:"size:63"`
+	OperatorRole    string `gorm:"size:63"`
+	FreeSubjectId   int64  // activity_free_subjects.id
+	UserId          int    `gorm:"index"`
+	SubjectId       int    `gorm:"index"`
+	SubjectFreeDays int
+	Reason          string `gorm:"size:255"`
+	CreatedAt       time.Time
+}
+
+func (b *BenefitGrantAudit) TableName() string {
+	return "benefit_grant_audits"
+}
@@ -6,31 +6,31 @@ import (
 )
 
 type ContentMessage struct {
-	Id              int64
+	Id              int64 `desc:"资讯 ID，news://{id} 资源可读取详情" query:"sort"`
 	AuthorId        int64
 	EditorId        int64
 	Style           string `gorm:"index"`
-	IsPremium       bool   `sql:"DEFAULT:0" gorm:"index"`
-	IsTrial         bool   `sql:"DEFAULT:0" gorm:"index"`
+	IsPremium       bool   `sql:"DEFAULT:0" gorm:"index" desc:"是否付费内容"`
+	IsTrial         bool   `sql:"DEFAULT:0" gorm:"index" desc:"是否试读内容"`
 	SubscribeType   string `sql:"DEFAULT:0" gorm:"index"`
 	IsPromotion     bool   `sql:"DEFAULT:0"`
-	Title           string `gorm:"index;unique_index:title_datestr"`
+	Title           string `gorm:"index;unique_index:title_datestr" desc:"标题"`
 	DateStr         string `gorm:"index;unique_index:title_datestr"`
-	Summary         string `gorm:"size:2047"`
-	Content         string `gorm:"type:mediumtext"`
+	Summary         string `gorm:"size:2047" desc:"摘要"`
+	Content         string `gorm:"type:mediumtext" desc:"正文（HTML），keyword 在正文中模糊匹配" query:"filter"`
 	PreviewContent  string `gorm:"type:mediumtext"`
 	Image           string
 	ImageType       string
 	AppFeedImg      string // 消息流里经过剪切的缩略图
 	PcImage         string
-	Url             string
+	Url             string `desc:"原文链接"`
 	MediaUrl        string
-	Source          string
-	DisplayAuthor   string
-	LikeCount       int32
+	Source          string `desc:"来源"`
+	DisplayAuthor   string `desc:"显示的作者"`
+	LikeCount       int32  `desc:"点赞数" query:"sort"`
 	DislikeCount    int32
-	PaidCount       int32 `gorm:"default:0;index"`
-	Impact          int32 `gorm:"default:0;index"`
+	PaidCount       int32 `gorm:"default:0;index" desc:"付费人数" query:"sort"`
+	Impact          int32 `gorm:"default:0;index" desc:"影响力分值" query:"sort"`
 	ContentType     sql.NullInt64
 	MediaType       sql.NullInt64
 	AuthorArticleId int64
@@ -42,21 +42,21 @@ type ContentMessage struct {
 	CrawlerResId    int64      `sql:"DEFAULT:0" gorm:"index"` // id of article in crawler's db
 	CrawlerWechatId int64      `sql:"DEFAULT:0" gorm:"index"` // which 公众号 is this article from
 	HasXgbXun       bool       `sql:"DEFAULT:1"`
-	CreatedAt       time.Time  `gorm:"index"`
+	CreatedAt       time.Time  `gorm:"index" desc:"发布时间，start_time、end_time 按该字段过滤" query:"filter,sort"`
 	OriginCreatedAt *time.Time // created_at, manual_updated_at 可能被修改，这里存储真实的创建时间。
 	// In a query clause like `where created_at > ? and updated_at < ?`,
 	// the following index might not be used, because it's a multi range
 	// query
-	UpdatedAt       time.Time `gorm:"index"`
-	ManualUpdatedAt time.Time `gorm:"index"`
+	UpdatedAt       time.Time `gorm:"index" desc:"更新时间" query:"sort"`
+	ManualUpdatedAt time.Time `gorm:"index" desc:"人工修改时间" query:"sort"`
 	// The following index is used to improved performance of PcNewMsgs()
 	// function, caution though it might interfere with index on
 	// `created_at` in a query clause like `where deleted_at is NULL and
 	// created_at > ?`
 	DeletedAt          *time.Time `gorm:"index"`
-	IsDeleted          bool       `sql:"DEFAULT:0"`
+	IsDeleted          bool       `sql:"DEFAULT:0" desc:"是否已删除"`
 	PreviewCount       int32      `gorm:"default:0"`
-	IsWithdrawn        bool       `gorm:"default:false;index"`
+	IsWithdrawn        bool       `gorm:"default:false;index" desc:"是否已撤回"`
 	AILimitationPeriod int        `gorm:"default:0"`       // hours
 	UseTempl           bool       `gorm:"index"`           // 是否使用文章模板
 	PrettyContent      string     `gorm:"type:mediumtext"` // 阅读模式处理过的文章正文
@@ -68,11 +68,11 @@ type ContentMessage struct {
 
 	WhetherHideImpactFace bool `gorm:"default:false"`
 
-	SubTitle string `gorm:"index"`
-	Score    int64  // wscn live 同步 1:默认样式 2:红 3:红加粗
+	SubTitle string `gorm:"index" desc:"副标题"`
+	Score    int64  `desc:"同步到快讯时的样式" enum:"1=默认样式,2=红,3=红加粗"` // wscn live 同步 1:默认样式 2:红 3:红加粗
 
 	CreatedBy     int64
-	IsTodaysFocus bool
+	IsTodaysFocus bool `desc:"是否今日焦点"`
 }
 
 func (c *ContentMessage) TableName() string {
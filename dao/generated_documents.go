@@ -0,0 +1,26 @@
+package dao
+
+import (
+	"time"
+)
+
+// GeneratedDocument 记录上传到 generated/ 下的每个文件，链接过期后可以据此重新签发。
+// 内容相同的文件复用同一个对象，因此多条记录可能指向同一个 ObjectName
+type GeneratedDocument struct {
+	ID          int64  `gorm:"primarykey"`
+	ObjectName  string `gorm:"size:512;index"`
+	ContentHash string `gorm:"size:64;index"` // 文件内容的 SHA-256
+	FileName    string `gorm:"size:255"`
+	FileType    string `gorm:"size:16"`
+	ContentType string `gorm:"size:127"`
+	Disposition string `gorm:"size:16"`
+	Size        int64
+	Creator     string `gorm:"size:63;index"`
+	CreatorRole string `gorm:"size:63"`
+	SessionId   string `gorm:"size:64;index"`
+	CreatedAt   time.Time
+}
+
+func (g *GeneratedDocument) TableName() string {
+	return "generated_documents"
+}
@@ -0,0 +1,37 @@
+package dao
+
+import (
+	"fmt"
+	"mcp/server/client"
+)
+
+// AutoMigrate 只创建本服务自己维护的表，业务表（user_users、content_messages 等）不在此列
+func AutoMigrate() error {
+	if err := client.Mysql.AutoMigrate(&BenefitGrantAudit{}, &GeneratedDocument{}); err != nil {
+		return err
+	}
+
+	return dropObjectNameUniqueIndex()
+}
+
+// dropObjectNameUniqueIndex generated_documents.object_name 最初是唯一索引，内容去重后多条记录会指向同一个对象。
+// 索引同名时 AutoMigrate 不会改动，这里把旧的唯一索引换成普通索引
+func dropObjectNameUniqueIndex() error {
+	const name = "idx_generated_documents_object_name"
+	m := client.Mysql.Migrator()
+	indexes, err := m.GetIndexes(&GeneratedDocument{})
+	if err != nil {
+		return err
+	}
+	for _, idx := range indexes {
+		if unique, ok := idx.Unique(); idx.Name() != name || !ok || !unique {
+			continue
+		}
+		if err := m.DropIndex(&GeneratedDocument{}, name); err != nil {
+			return fmt.Errorf("drop unique index %s: %w", name, err)
+		}
+		return m.CreateIndex(&GeneratedDocument{}, "ObjectName")
+	}
+
+	return nil
+}
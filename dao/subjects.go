@@ -0,0 +1,19 @@
+package dao
+
+import (
+	"mcp/server/client"
+)
+
+type Subject struct {
+	Id    int
+	Title string
+}
+
+func ListSubjects(table string) ([]Subject, error) {
+	var subjects []Subject
+	if err := client.Mysql.Table(table).Select("id, title").Scan(&subjects).Error; err != nil {
+		return nil, err
+	}
+
+	return subjects, nil
+}
@@ -6,10 +6,10 @@ import (
 )
 
 type UserModel struct {
-	Id            int64
-	Username      sql.NullString `gorm:"size:63;unique_index"`
-	Email         sql.NullString `gorm:"size:63;unique_index"`
-	Mobile        sql.NullString `gorm:"size:15;unique_index"`
+	Id            int64          `desc:"用户 ID" query:"sort"`
+	Username      sql.NullString `gorm:"size:63;unique_index" desc:"用户名"`
+	Email         sql.NullString `gorm:"size:63;unique_index" desc:"邮箱，返回时脱敏"`
+	Mobile        sql.NullString `gorm:"size:15;unique_index" desc:"手机号，返回时脱敏"`
 	PasswordSalt  []byte         `gorm:"size:64"`
 	PasswordHash  []byte         `gorm:"size:64"`
 	WeiboId       sql.NullString `gorm:"size:63;unique_index"`
@@ -19,20 +19,20 @@ type UserModel struct {
 	OAuth1Id      sql.NullString `gorm:"size:63;unique_index"`
 	// Only store appToken because webTokens are short lived
 	AppToken      sql.NullString `gorm:"size:127"`
-	Manufacturer  sql.NullString `gorm:"size:127"`
-	PushProvider  sql.NullString `gorm:"size:16"`
+	Manufacturer  sql.NullString `gorm:"size:127" desc:"手机厂商，例如 Huawei" query:"filter"`
+	PushProvider  sql.NullString `gorm:"size:16" desc:"推送通道" query:"filter"`
 	PushToken     sql.NullString `gorm:"size:255"`
-	LastDeviceId  sql.NullString `gorm:"size:127"` // 用于追踪设备有没有注册过
-	ClientVersion sql.NullString `gorm:"size:32"`  // 记录客户端版本号
-	Nickname      sql.NullString `gorm:"size:63"`
-	RealName      sql.NullString `gorm:"size:63"`
+	LastDeviceId  sql.NullString `gorm:"size:127"`                             // 用于追踪设备有没有注册过
+	ClientVersion sql.NullString `gorm:"size:32" desc:"客户端版本号" query:"filter"` // 记录客户端版本号
+	Nickname      sql.NullString `gorm:"size:63" desc:"昵称"`
+	RealName      sql.NullString `gorm:"size:63" desc:"真实姓名，返回时脱敏"`
 	Portrait      sql.NullString `gorm:"size:255"`
-	BannedUntil   *time.Time
-	CreatedAt     time.Time
-	LastActiveAt  time.Time `gorm:"index"`
-	DeletedAt     *time.Time
-	PlatformName  string `gorm:"index"`
-	XgbChannel    string `gorm:"index"`
+	BannedUntil   *time.Time     `desc:"封禁截止时间，banned 参数按该字段过滤" query:"filter"`
+	CreatedAt     time.Time      `desc:"注册时间，start_time、end_time 按该字段过滤" query:"filter,sort"`
+	LastActiveAt  time.Time      `gorm:"index" desc:"最近活跃时间，last_active_start、last_active_end 按该字段过滤" query:"filter,sort"`
+	DeletedAt     *time.Time     `desc:"注销时间，不为空表示已注销，默认不返回已注销用户" query:"filter"`
+	PlatformName  string         `gorm:"index" desc:"平台名称，例如 ios、android" query:"filter"`
+	XgbChannel    string         `gorm:"index" desc:"渠道" query:"filter"`
 
 	QqUnionId sql.NullString `gorm:"size:63;index"`
 
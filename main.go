@@ -1,12 +1,17 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"github.com/mark3labs/mcp-go/server"
 	"log"
+	"mcp/server/auth"
 	"mcp/server/client"
 	"mcp/server/config"
+	"mcp/server/dao"
+	"mcp/server/storage"
 	"mcp/server/tools"
+	"net/http"
 	"os"
 )
 
@@ -18,16 +23,34 @@ func main() {
 
 	cfg := config.Cfg
 	client.InitMysql(cfg.Mysql)
+	if err := dao.AutoMigrate(); err != nil {
+		log.Fatalln("auto migrate failed, err ", err)
+	}
 	client.InitQdrant(cfg.Qdrant)
 	client.InitLLMs(cfg.OpenAI)
-	client.InitMinIO(cfg.MinIO)
+	storage.Init(cfg)
 	defer client.Close()
 
-	mcpServer := server.NewMCPServer("rag_finance_news_tools", "1.0.0")
+	mcpServer := server.NewMCPServer("rag_finance_news_tools", "1.0.0",
+		server.WithElicitation(),
+		server.WithCompletions(),
+		server.WithPromptCompletionProvider(tools.NewPromptCompletionProvider()),
+	)
 
 	tools.RegisterTools(mcpServer)
-
-	httpServer := server.NewStreamableHTTPServer(mcpServer)
+	tools.RegisterResources(mcpServer)
+	tools.RegisterPrompts(mcpServer)
+	tools.StartRetention(context.Background())
+	tools.CheckReportFonts()
+
+	// local、memory 存储的文件下载和上传与 MCP 接口共用同一个端口
+	mux := http.NewServeMux()
+	httpServer := server.NewStreamableHTTPServer(mcpServer,
+		server.WithHTTPContextFunc(auth.HTTPContextFunc),
+		server.WithStreamableHTTPServer(&http.Server{Handler: mux}),
+	)
+	mux.Handle("/mcp", httpServer)
+	mux.Handle(storage.FilesPath, storage.Handler())
 
 	log.Println("Starting StreamableHTTP server on :8085")
 	if err := httpServer.Start(":8085"); err != nil {
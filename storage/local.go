@@ -0,0 +1,224 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"iter"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// metaDir 保存对象元数据（类型、Content-Disposition）的目录，列举对象时跳过
+const metaDir = ".meta"
+
+type localMeta struct {
+	ContentType        string `json:"content_type"`
+	ContentDisposition string `json:"content_disposition,omitempty"`
+}
+
+// localStore 把对象保存在本地目录中，适合没有 MinIO 的小规模部署，下载链接由 MCP 服务自身提供
+type localStore struct {
+	root   string
+	signer *urlSigner
+}
+
+func newLocalStore(dir string, signer *urlSigner) (ObjectStore, error) {
+	if dir == "" {
+		dir = "data/objects"
+	}
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Join(root, metaDir), 0o755); err != nil {
+		return nil, err
+	}
+
+	return &localStore{root: root, signer: signer}, nil
+}
+
+func (l *localStore) path(key string) (string, error) {
+	if err := validKey(key); err != nil {
+		return "", err
+	}
+	if key == metaDir || strings.HasPrefix(key, metaDir+"/") {
+		return "", fmt.Errorf("invalid object key %q", key)
+	}
+
+	return filepath.Join(l.root, filepath.FromSlash(key)), nil
+}
+
+func (l *localStore) metaPath(key string) string {
+	return filepath.Join(l.root, metaDir, filepath.FromSlash(key)+".json")
+}
+
+func (l *localStore) Put(ctx context.Context, key string, reader io.Reader, size int64, opts PutOptions) (ObjectInfo, error) {
+	p, err := l.path(key)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return ObjectInfo{}, err
+	}
+
+	// 先写临时文件再改名，读取方不会看到写了一半的文件
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".upload-*")
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	defer os.Remove(tmp.Name())
+	written, err := io.Copy(tmp, reader)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	if size >= 0 && written != size {
+		return ObjectInfo{}, fmt.Errorf("expected %d bytes, got %d", size, written)
+	}
+
+	meta, _ := json.Marshal(localMeta{ContentType: opts.ContentType, ContentDisposition: opts.ContentDisposition})
+	if err := os.MkdirAll(filepath.Dir(l.metaPath(key)), 0o755); err != nil {
+		return ObjectInfo{}, err
+	}
+	if err := os.WriteFile(l.metaPath(key), meta, 0o644); err != nil {
+		return ObjectInfo{}, err
+	}
+	if err := os.Rename(tmp.Name(), p); err != nil {
+		return ObjectInfo{}, err
+	}
+
+	return ObjectInfo{Key: key, Size: written, ContentType: opts.ContentType, LastModified: time.Now()}, nil
+}
+
+func (l *localStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	p, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+
+	return f, err
+}
+
+func (l *localStore) Stat(ctx context.Context, key string) (ObjectInfo, error) {
+	p, err := l.path(key)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	fi, err := os.Stat(p)
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && fi.IsDir()) {
+		return ObjectInfo{}, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+
+	return ObjectInfo{Key: key, Size: fi.Size(), ContentType: l.readMeta(key).ContentType, LastModified: fi.ModTime()}, nil
+}
+
+func (l *localStore) readMeta(key string) localMeta {
+	meta := localMeta{ContentType: "application/octet-stream"}
+	if data, err := os.ReadFile(l.metaPath(key)); err == nil {
+		_ = json.Unmarshal(data, &meta)
+	}
+
+	return meta
+}
+
+func (l *localStore) Remove(ctx context.Context, key string) error {
+	p, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if err := os.Remove(l.metaPath(key)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+func (l *localStore) List(ctx context.Context, prefix string) iter.Seq2[ObjectInfo, error] {
+	return func(yield func(ObjectInfo, error) bool) {
+		// 从前缀所在的目录开始遍历，避免扫描整个存储目录
+		start := l.root
+		if dir := path.Dir(prefix); dir != "." {
+			start = filepath.Join(l.root, filepath.FromSlash(dir))
+		}
+
+		err := filepath.WalkDir(start, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					return nil
+				}
+				return err
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if d.Name() == metaDir {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if strings.HasPrefix(d.Name(), ".upload-") {
+				return nil
+			}
+
+			rel, err := filepath.Rel(l.root, p)
+			if err != nil {
+				return err
+			}
+			key := filepath.ToSlash(rel)
+			if !strings.HasPrefix(key, prefix) {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			if !yield(ObjectInfo{Key: key, Size: info.Size(), ContentType: l.readMeta(key).ContentType, LastModified: info.ModTime()}, nil) {
+				return filepath.SkipAll
+			}
+			return nil
+		})
+		if err != nil {
+			yield(ObjectInfo{}, err)
+		}
+	}
+}
+
+func (l *localStore) PresignGet(ctx context.Context, key string, expiry time.Duration, disposition string) (string, error) {
+	if err := validKey(key); err != nil {
+		return "", err
+	}
+
+	return l.signer.presignGet(key, disposition, expiry), nil
+}
+
+func (l *localStore) PresignUpload(ctx context.Context, key string, expiry time.Duration, contentType string, maxSize int64) (*PresignedUpload, error) {
+	if err := validKey(key); err != nil {
+		return nil, err
+	}
+
+	return &PresignedUpload{PutURL: l.signer.presignPut(key, contentType, maxSize, expiry)}, nil
+}
+
+func (l *localStore) KeyFromURL(rawURL string) (string, bool) {
+	return l.signer.keyFromURL(rawURL)
+}
@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"iter"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+type memoryObject struct {
+	data []byte
+	info ObjectInfo
+}
+
+// memoryStore 把对象保存在内存中，重启后丢失，用于测试和本地调试
+type memoryStore struct {
+	mu      sync.RWMutex
+	objects map[string]*memoryObject
+	signer  *urlSigner
+}
+
+func newMemoryStore(signer *urlSigner) ObjectStore {
+	return &memoryStore{objects: make(map[string]*memoryObject), signer: signer}
+}
+
+func (m *memoryStore) Put(ctx context.Context, key string, reader io.Reader, size int64, opts PutOptions) (ObjectInfo, error) {
+	if err := validKey(key); err != nil {
+		return ObjectInfo{}, err
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	if size >= 0 && int64(len(data)) != size {
+		return ObjectInfo{}, fmt.Errorf("expected %d bytes, got %d", size, len(data))
+	}
+
+	info := ObjectInfo{Key: key, Size: int64(len(data)), ContentType: opts.ContentType, LastModified: time.Now()}
+	m.mu.Lock()
+	m.objects[key] = &memoryObject{data: data, info: info}
+	m.mu.Unlock()
+
+	return info, nil
+}
+
+func (m *memoryStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	m.mu.RLock()
+	obj, ok := m.objects[key]
+	m.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+
+	return io.NopCloser(bytes.NewReader(obj.data)), nil
+}
+
+func (m *memoryStore) Stat(ctx context.Context, key string) (ObjectInfo, error) {
+	m.mu.RLock()
+	obj, ok := m.objects[key]
+	m.mu.RUnlock()
+	if !ok {
+		return ObjectInfo{}, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+
+	return obj.info, nil
+}
+
+func (m *memoryStore) Remove(ctx context.Context, key string) error {
+	m.mu.Lock()
+	delete(m.objects, key)
+	m.mu.Unlock()
+
+	return nil
+}
+
+func (m *memoryStore) List(ctx context.Context, prefix string) iter.Seq2[ObjectInfo, error] {
+	return func(yield func(ObjectInfo, error) bool) {
+		m.mu.RLock()
+		var infos []ObjectInfo
+		for _, key := range slices.Sorted(maps.Keys(m.objects)) {
+			if strings.HasPrefix(key, prefix) {
+				infos = append(infos, m.objects[key].info)
+			}
+		}
+		m.mu.RUnlock()
+
+		for _, info := range infos {
+			if !yield(info, nil) {
+				return
+			}
+		}
+	}
+}
+
+func (m *memoryStore) PresignGet(ctx context.Context, key string, expiry time.Duration, disposition string) (string, error) {
+	if err := validKey(key); err != nil {
+		return "", err
+	}
+
+	return m.signer.presignGet(key, disposition, expiry), nil
+}
+
+func (m *memoryStore) PresignUpload(ctx context.Context, key string, expiry time.Duration, contentType string, maxSize int64) (*PresignedUpload, error) {
+	if err := validKey(key); err != nil {
+		return nil, err
+	}
+
+	return &PresignedUpload{PutURL: m.signer.presignPut(key, contentType, maxSize, expiry)}, nil
+}
+
+func (m *memoryStore) KeyFromURL(rawURL string) (string, bool) {
+	return m.signer.keyFromURL(rawURL)
+}
@@ -0,0 +1,141 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/lifecycle"
+	"io"
+	"iter"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// streamPartSize 长度未知时的分片大小。不指定时 minio-go 按 5TiB 计算分片，每个分片要在内存中缓冲数百 MB
+const streamPartSize = 16 << 20
+
+type minioStore struct {
+	client *minio.Client
+	bucket string
+}
+
+func newMinIOStore(client *minio.Client, bucket string) ObjectStore {
+	return &minioStore{client: client, bucket: bucket}
+}
+
+func (m *minioStore) Put(ctx context.Context, key string, reader io.Reader, size int64, opts PutOptions) (ObjectInfo, error) {
+	putOpts := minio.PutObjectOptions{
+		ContentType:        opts.ContentType,
+		ContentDisposition: opts.ContentDisposition,
+	}
+	if size < 0 {
+		// 长度未知时按分片上传，失败时 minio-go 会中止分片上传并清理已上传的分片
+		putOpts.PartSize = streamPartSize
+	}
+	info, err := m.client.PutObject(ctx, m.bucket, key, reader, size, putOpts)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+
+	return ObjectInfo{Key: key, Size: info.Size, ContentType: opts.ContentType, LastModified: info.LastModified}, nil
+}
+
+func (m *minioStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	return m.client.GetObject(ctx, m.bucket, key, minio.GetObjectOptions{})
+}
+
+func (m *minioStore) Stat(ctx context.Context, key string) (ObjectInfo, error) {
+	info, err := m.client.StatObject(ctx, m.bucket, key, minio.StatObjectOptions{})
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return ObjectInfo{}, fmt.Errorf("%w: %s", ErrNotFound, key)
+		}
+		return ObjectInfo{}, err
+	}
+
+	return ObjectInfo{Key: key, Size: info.Size, ContentType: info.ContentType, LastModified: info.LastModified}, nil
+}
+
+func (m *minioStore) Remove(ctx context.Context, key string) error {
+	return m.client.RemoveObject(ctx, m.bucket, key, minio.RemoveObjectOptions{})
+}
+
+func (m *minioStore) List(ctx context.Context, prefix string) iter.Seq2[ObjectInfo, error] {
+	return func(yield func(ObjectInfo, error) bool) {
+		for obj := range m.client.ListObjects(ctx, m.bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+			if obj.Err != nil {
+				yield(ObjectInfo{}, obj.Err)
+				return
+			}
+			if !yield(ObjectInfo{Key: obj.Key, Size: obj.Size, ContentType: obj.ContentType, LastModified: obj.LastModified}, nil) {
+				return
+			}
+		}
+	}
+}
+
+func (m *minioStore) PresignGet(ctx context.Context, key string, expiry time.Duration, disposition string) (string, error) {
+	reqParams := make(url.Values)
+	reqParams.Set("response-content-disposition", disposition)
+	u, err := m.client.PresignedGetObject(ctx, m.bucket, key, expiry, reqParams)
+	if err != nil {
+		return "", err
+	}
+
+	return u.String(), nil
+}
+
+func (m *minioStore) PresignUpload(ctx context.Context, key string, expiry time.Duration, contentType string, maxSize int64) (*PresignedUpload, error) {
+	putURL, err := m.client.PresignedPutObject(ctx, m.bucket, key, expiry)
+	if err != nil {
+		return nil, err
+	}
+
+	// 表单方式上传时由 MinIO 校验类型和大小
+	policy := minio.NewPostPolicy()
+	_ = policy.SetBucket(m.bucket)
+	_ = policy.SetKey(key)
+	_ = policy.SetExpires(time.Now().UTC().Add(expiry))
+	_ = policy.SetContentType(contentType)
+	_ = policy.SetContentLengthRange(1, maxSize)
+	postURL, formData, err := m.client.PresignedPostPolicy(ctx, policy)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PresignedUpload{PutURL: putURL.String(), PostURL: postURL.String(), FormData: formData}, nil
+}
+
+func (m *minioStore) KeyFromURL(rawURL string) (string, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host != m.client.EndpointURL().Host {
+		return "", false
+	}
+
+	return strings.CutPrefix(u.Path, "/"+m.bucket+"/")
+}
+
+// SetExpiration 在桶原有的生命周期规则上增加或替换 ruleId 对应的规则
+func (m *minioStore) SetExpiration(ctx context.Context, ruleId, prefix string, days int) error {
+	lc, err := m.client.GetBucketLifecycle(ctx, m.bucket)
+	if err != nil {
+		if minio.ToErrorResponse(err).Code != "NoSuchLifecycleConfiguration" {
+			return err
+		}
+		lc = lifecycle.NewConfiguration()
+	}
+
+	lc.Rules = slices.DeleteFunc(lc.Rules, func(r lifecycle.Rule) bool {
+		return r.ID == ruleId
+	})
+	lc.Rules = append(lc.Rules, lifecycle.Rule{
+		ID:         ruleId,
+		Status:     "Enabled",
+		RuleFilter: lifecycle.Filter{Prefix: prefix},
+		Expiration: lifecycle.Expiration{Days: lifecycle.ExpirationDays(days)},
+	})
+
+	return m.client.SetBucketLifecycle(ctx, m.bucket, lc)
+}
@@ -0,0 +1,221 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// urlSigner 为 local、memory 存储签发由本服务提供的下载和上传链接，链接参数用 HMAC-SHA256 签名
+type urlSigner struct {
+	baseURL string
+	key     []byte
+}
+
+func newURLSigner(publicURL, signingKey string) *urlSigner {
+	key := []byte(signingKey)
+	if len(key) == 0 {
+		// 未配置密钥时随机生成，服务重启后之前签发的链接失效
+		key = make([]byte, 32)
+		_, _ = rand.Read(key)
+		log.Println("storage.signingKey 未配置，使用随机密钥，重启后下载链接失效")
+	}
+	if publicURL == "" {
+		publicURL = "http://127.0.0.1:8085"
+	}
+
+	return &urlSigner{baseURL: strings.TrimRight(publicURL, "/"), key: key}
+}
+
+func (s *urlSigner) sign(parts ...string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(strings.Join(parts, "\n")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *urlSigner) objectURL(key string, query url.Values) string {
+	segments := strings.Split(key, "/")
+	for i, seg := range segments {
+		segments[i] = url.PathEscape(seg)
+	}
+
+	return s.baseURL + FilesPath + strings.Join(segments, "/") + "?" + query.Encode()
+}
+
+func (s *urlSigner) presignGet(key, disposition string, expiry time.Duration) string {
+	expires := strconv.FormatInt(time.Now().Add(expiry).Unix(), 10)
+	query := url.Values{
+		"expires":     {expires},
+		"disposition": {disposition},
+		"signature":   {s.sign(http.MethodGet, key, expires, disposition)},
+	}
+
+	return s.objectURL(key, query)
+}
+
+func (s *urlSigner) presignPut(key, contentType string, maxSize int64, expiry time.Duration) string {
+	expires := strconv.FormatInt(time.Now().Add(expiry).Unix(), 10)
+	size := strconv.FormatInt(maxSize, 10)
+	query := url.Values{
+		"expires":      {expires},
+		"content_type": {contentType},
+		"max_size":     {size},
+		"signature":    {s.sign(http.MethodPut, key, expires, contentType, size)},
+	}
+
+	return s.objectURL(key, query)
+}
+
+func (s *urlSigner) keyFromURL(rawURL string) (string, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", false
+	}
+	base, err := url.Parse(s.baseURL)
+	if err != nil || u.Host != base.Host {
+		return "", false
+	}
+
+	return strings.CutPrefix(u.Path, strings.TrimRight(base.Path, "/")+FilesPath)
+}
+
+// verify 校验签名和有效期，parts 为除方法、对象名、过期时间以外参与签名的参数
+func (s *urlSigner) verify(method, key string, query url.Values, parts ...string) error {
+	expires := query.Get("expires")
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return errors.New("invalid expires")
+	}
+	if time.Now().Unix() > unix {
+		return errors.New("link has expired")
+	}
+
+	expected := s.sign(append([]string{method, key, expires}, parts...)...)
+	if !hmac.Equal([]byte(expected), []byte(query.Get("signature"))) {
+		return errors.New("invalid signature")
+	}
+
+	return nil
+}
+
+// fileHandler 处理签名链接的下载（GET）和上传（PUT）
+type fileHandler struct {
+	store  ObjectStore
+	signer *urlSigner
+}
+
+func newFileHandler(store ObjectStore, signer *urlSigner) http.Handler {
+	return &fileHandler{store: store, signer: signer}
+}
+
+func (h *fileHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := strings.TrimPrefix(r.URL.Path, FilesPath)
+	if err := validKey(key); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		disposition := query.Get("disposition")
+		if err := h.signer.verify(http.MethodGet, key, query, disposition); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		h.serveObject(w, r, key, disposition)
+	case http.MethodPut:
+		contentType := query.Get("content_type")
+		maxSize, _ := strconv.ParseInt(query.Get("max_size"), 10, 64)
+		if err := h.signer.verify(http.MethodPut, key, query, contentType, query.Get("max_size")); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		h.receiveObject(w, r, key, contentType, maxSize)
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PUT")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *fileHandler) serveObject(w http.ResponseWriter, r *http.Request, key, disposition string) {
+	info, err := h.store.Stat(r.Context(), key)
+	if errors.Is(err, ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", info.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(info.Size, 10))
+	w.Header().Set("Last-Modified", info.LastModified.UTC().Format(http.TimeFormat))
+	// 文件内容来自调用方，SVG、HTML 在本服务的源下直接打开时不能运行脚本
+	w.Header().Set("Content-Security-Policy", "sandbox")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if disposition != "" {
+		w.Header().Set("Content-Disposition", disposition)
+	}
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	obj, err := h.store.Get(r.Context(), key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer obj.Close()
+	_, _ = io.Copy(w, obj)
+}
+
+// receiveObject 与 MinIO 的表单上传一样校验类型和大小
+func (h *fileHandler) receiveObject(w http.ResponseWriter, r *http.Request, key, contentType string, maxSize int64) {
+	if got := r.Header.Get("Content-Type"); got != contentType {
+		http.Error(w, fmt.Sprintf("Content-Type must be %s", contentType), http.StatusBadRequest)
+		return
+	}
+	if r.ContentLength > maxSize {
+		http.Error(w, fmt.Sprintf("file exceeds limit of %d bytes", maxSize), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	body := http.MaxBytesReader(w, r.Body, maxSize)
+	if _, err := h.store.Put(r.Context(), key, body, r.ContentLength, PutOptions{ContentType: contentType}); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, fmt.Sprintf("file exceeds limit of %d bytes", maxSize), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// validKey 对象名不能为空，不能是绝对路径，也不能包含 ..
+func validKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return fmt.Errorf("invalid object key %q", key)
+	}
+	for _, seg := range strings.Split(key, "/") {
+		if seg == "" || seg == "." || seg == ".." {
+			return fmt.Errorf("invalid object key %q", key)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,134 @@
+package storage
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func parseSignedURL(t *testing.T, rawURL string) (string, url.Values) {
+	t.Helper()
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatalf("parse %q: %v", rawURL, err)
+	}
+
+	return u.Path, u.Query()
+}
+
+func TestURLSignerPresignGet(t *testing.T) {
+	signer := newURLSigner("http://files.example.com/base/", "secret")
+	link := signer.presignGet("generated/报告 1.pdf", "inline", time.Hour)
+
+	key, ok := signer.keyFromURL(link)
+	if !ok || key != "generated/报告 1.pdf" {
+		t.Fatalf("keyFromURL(%q) = %q, %v", link, key, ok)
+	}
+	_, query := parseSignedURL(t, link)
+	if err := signer.verify(http.MethodGet, key, query, query.Get("disposition")); err != nil {
+		t.Fatalf("verify fresh link: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		key    string
+		modify func(q url.Values)
+	}{
+		{"other key", http.MethodGet, "generated/other.pdf", func(url.Values) {}},
+		{"other method", http.MethodPut, key, func(url.Values) {}},
+		{"tampered disposition", http.MethodGet, key, func(q url.Values) { q.Set("disposition", "attachment") }},
+		{"extended expiry", http.MethodGet, key, func(q url.Values) { q.Set("expires", "99999999999") }},
+		{"missing signature", http.MethodGet, key, func(q url.Values) { q.Del("signature") }},
+		{"invalid expires", http.MethodGet, key, func(q url.Values) { q.Set("expires", "soon") }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := url.Values{}
+			for k, v := range query {
+				q[k] = append([]string(nil), v...)
+			}
+			tt.modify(q)
+			if err := signer.verify(tt.method, tt.key, q, q.Get("disposition")); err == nil {
+				t.Fatal("verify succeeded, want error")
+			}
+		})
+	}
+}
+
+func TestURLSignerExpiredAndWrongKey(t *testing.T) {
+	signer := newURLSigner("http://files.example.com", "secret")
+
+	_, query := parseSignedURL(t, signer.presignGet("generated/a.csv", "attachment", -time.Minute))
+	if err := signer.verify(http.MethodGet, "generated/a.csv", query, "attachment"); err == nil {
+		t.Fatal("expired link verified")
+	}
+
+	_, query = parseSignedURL(t, signer.presignGet("generated/a.csv", "attachment", time.Hour))
+	other := newURLSigner("http://files.example.com", "another-secret")
+	if err := other.verify(http.MethodGet, "generated/a.csv", query, "attachment"); err == nil {
+		t.Fatal("link signed with another key verified")
+	}
+}
+
+func TestURLSignerPresignPut(t *testing.T) {
+	signer := newURLSigner("http://files.example.com", "secret")
+	_, query := parseSignedURL(t, signer.presignPut("uploads/ids.csv", "text/csv", 1<<20, time.Hour))
+
+	if err := signer.verify(http.MethodPut, "uploads/ids.csv", query, "text/csv", "1048576"); err != nil {
+		t.Fatalf("verify upload link: %v", err)
+	}
+	// 上传大小上限和类型都参与签名，不能被调大或替换
+	if err := signer.verify(http.MethodPut, "uploads/ids.csv", query, "text/csv", "2097152"); err == nil {
+		t.Fatal("verify succeeded with a larger max_size")
+	}
+	if err := signer.verify(http.MethodPut, "uploads/ids.csv", query, "application/pdf", "1048576"); err == nil {
+		t.Fatal("verify succeeded with another content type")
+	}
+}
+
+func TestURLSignerKeyFromURL(t *testing.T) {
+	signer := newURLSigner("http://files.example.com/base", "secret")
+	tests := []struct {
+		url  string
+		key  string
+		want bool
+	}{
+		{"http://files.example.com/base/files/generated/a.csv?expires=1", "generated/a.csv", true},
+		{"http://files.example.com/base/files/generated/%E6%8A%A5%E5%91%8A.pdf", "generated/报告.pdf", true},
+		{"http://evil.example.com/base/files/generated/a.csv", "", false},
+		{"http://files.example.com/other/files/generated/a.csv", "", false},
+		{"://bad", "", false},
+	}
+	for _, tt := range tests {
+		key, ok := signer.keyFromURL(tt.url)
+		if ok != tt.want || (ok && key != tt.key) {
+			t.Errorf("keyFromURL(%q) = %q, %v, want %q, %v", tt.url, key, ok, tt.key, tt.want)
+		}
+	}
+}
+
+func TestValidKey(t *testing.T) {
+	tests := []struct {
+		key   string
+		valid bool
+	}{
+		{"generated/a.csv", true},
+		{"uploads/2026/ids.csv", true},
+		{"a.csv", true},
+		{"", false},
+		{"/etc/passwd", false},
+		{"generated/../secret", false},
+		{"generated/./a.csv", false},
+		{"generated//a.csv", false},
+		{"generated/", false},
+		{`generated\a.csv`, false},
+		{"..", false},
+	}
+	for _, tt := range tests {
+		if err := validKey(tt.key); (err == nil) != tt.valid {
+			t.Errorf("validKey(%q) = %v, want valid=%v", tt.key, err, tt.valid)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"io"
+	"iter"
+	"log"
+	"mcp/server/client"
+	"mcp/server/config"
+	"net/http"
+	"time"
+)
+
+// ObjectInfo 对象的基本信息
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	ContentType  string
+	LastModified time.Time
+}
+
+type PutOptions struct {
+	ContentType        string
+	ContentDisposition string
+}
+
+// PresignedUpload 预签名上传地址；PostURL 为空表示该存储只支持 PUT 方式上传
+type PresignedUpload struct {
+	PutURL   string
+	PostURL  string
+	FormData map[string]string
+}
+
+var ErrNotFound = errors.New("object not found")
+
+// ObjectStore 生成文件和用户上传文件的存储，工具只通过它读写对象，不直接依赖 MinIO
+type ObjectStore interface {
+	// Put size 为 -1 时表示长度未知，reader 会被流式读取，MinIO 使用分片上传
+	Put(ctx context.Context, key string, reader io.Reader, size int64, opts PutOptions) (ObjectInfo, error)
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Stat 对象不存在时返回的错误包含 ErrNotFound
+	Stat(ctx context.Context, key string) (ObjectInfo, error)
+	Remove(ctx context.Context, key string) error
+	List(ctx context.Context, prefix string) iter.Seq2[ObjectInfo, error]
+	// PresignGet disposition 为完整的 Content-Disposition 响应头
+	PresignGet(ctx context.Context, key string, expiry time.Duration, disposition string) (string, error)
+	// PresignUpload 上传时必须使用 contentType，大小不能超过 maxSize
+	PresignUpload(ctx context.Context, key string, expiry time.Duration, contentType string, maxSize int64) (*PresignedUpload, error)
+	// KeyFromURL 从本存储签发的下载链接中取出对象名
+	KeyFromURL(rawURL string) (string, bool)
+}
+
+// ExpirationSetter 支持按前缀自动过期对象的存储（MinIO 生命周期规则）
+type ExpirationSetter interface {
+	SetExpiration(ctx context.Context, ruleId, prefix string, days int) error
+}
+
+// FilesPath local、memory 存储的下载和上传链接由 MCP 服务自身在该路径下提供
+const FilesPath = "/files/"
+
+var (
+	Store   ObjectStore
+	handler http.Handler = http.NotFoundHandler()
+)
+
+// Init 按配置选择存储后端，默认使用 MinIO
+func Init(cfg *config.Config) {
+	sc := config.StorageConfig{}
+	if cfg.Storage != nil {
+		sc = *cfg.Storage
+	}
+
+	switch sc.Backend {
+	case "local":
+		signer := newURLSigner(sc.PublicURL, sc.SigningKey)
+		store, err := newLocalStore(sc.Dir, signer)
+		if err != nil {
+			log.Fatalln("初始化本地存储失败:", err)
+		}
+		Store, handler = store, newFileHandler(store, signer)
+	case "memory":
+		signer := newURLSigner(sc.PublicURL, sc.SigningKey)
+		store := newMemoryStore(signer)
+		Store, handler = store, newFileHandler(store, signer)
+	default:
+		client.InitMinIO(cfg.MinIO)
+		Store = newMinIOStore(client.MinIO, cfg.MinIO.BucketName)
+	}
+	log.Printf("object storage backend: %s", cmp.Or(sc.Backend, "minio"))
+}
+
+// Handler 提供 local、memory 存储的签名下载和上传，需要挂载到 FilesPath
+func Handler() http.Handler {
+	return handler
+}
@@ -0,0 +1,210 @@
+package tools
+
+import (
+	"archive/zip"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/mark3labs/mcp-go/mcp"
+	"gorm.io/gorm"
+	"io"
+	"mcp/server/client"
+	"mcp/server/dao"
+	"mcp/server/storage"
+	"path"
+	"strings"
+	"time"
+)
+
+// maxBundleItems 单个压缩包最多包含的文件数
+const maxBundleItems = 50
+
+func getBundleDocumentsTool() mcp.Tool {
+	tool := mcp.NewTool("bundle_documents",
+		mcp.WithDescription(`
+把多个文件打包成一个 zip 压缩包，只返回一个下载链接和压缩包内容清单。
+适合把导出的数据表、markdown 总结和图表一起交给用户。每个条目三选一：
+- handle：之前生成的文件，可以是 generate_chart 返回的 handle，也可以是本服务签发的下载链接（generate_document_link、export_query、render_report 返回的 url）
+- document_id：list_documents 返回的文件 ID
+- content：直接传入的文本内容，必须同时指定 name（含扩展名，例如 summary.md）
+`),
+		mcp.WithInputSchema[BundleDocumentsReq](),
+		mcp.WithOutputSchema[BundleResult](),
+	)
+	return tool
+}
+
+type BundleItem struct {
+	Handle     string `json:"handle,omitempty" jsonschema_description:"之前生成的文件的 handle 或下载链接"`
+	DocumentId int64  `json:"document_id,omitempty" jsonschema_description:"list_documents 返回的文件 ID"`
+	Content    string `json:"content,omitempty" jsonschema_description:"直接写入压缩包的文本内容"`
+	Name       string `json:"name,omitempty" jsonschema_description:"压缩包内的文件名（含扩展名），content 条目必填，其他条目默认使用原文件名"`
+}
+
+type BundleDocumentsReq struct {
+	Items         []BundleItem `json:"items" jsonschema_description:"要打包的文件，最多 50 个"`
+	Filename      string       `json:"filename,omitempty" jsonschema_description:"压缩包文件名（不含扩展名），默认 bundle"`
+	ExpiryMinutes int          `json:"expiry_minutes,omitempty" jsonschema_description:"下载链接有效期（分钟），不填使用服务端默认值，最长 7 天"`
+}
+
+type BundleEntry struct {
+	Name string `json:"name"`
+	// handle、document 或 inline
+	Source string `json:"source"`
+	Size   int64  `json:"size"`
+}
+
+type BundleResult struct {
+	URL       string        `json:"url"`
+	ExpiresAt time.Time     `json:"expires_at"`
+	Manifest  []BundleEntry `json:"manifest"`
+}
+
+// bundleSource 压缩包中的一个条目，objectName 为空时写入 content
+type bundleSource struct {
+	entry      BundleEntry
+	objectName string
+	content    string
+}
+
+func bundleDocuments(ctx context.Context, request mcp.CallToolRequest, br BundleDocumentsReq) (*BundleResult, error) {
+	if len(br.Items) == 0 {
+		return nil, errors.New("items is required")
+	}
+	if len(br.Items) > maxBundleItems {
+		return nil, fmt.Errorf("at most %d items can be bundled", maxBundleItems)
+	}
+
+	// 先解析并检查全部条目，有条目不可用时不开始上传
+	sources := make([]bundleSource, 0, len(br.Items))
+	names := make(map[string]int, len(br.Items))
+	for i, item := range br.Items {
+		src, err := resolveBundleItem(ctx, item)
+		if err != nil {
+			return nil, fmt.Errorf("items[%d]: %w", i, err)
+		}
+		src.entry.Name = uniqueEntryName(names, src.entry.Name)
+		sources = append(sources, src)
+	}
+
+	filename := br.Filename
+	if filename == "" {
+		filename = "bundle"
+	}
+	doc, err := writeDocument(ctx, filename, ".zip", "application/zip", "attachment", "", linkExpiry(br.ExpiryMinutes), func(w io.Writer) error {
+		zw := zip.NewWriter(w)
+		for _, src := range sources {
+			if err := writeBundleEntry(ctx, zw, src); err != nil {
+				return fmt.Errorf("write %s failed: %w", src.entry.Name, err)
+			}
+		}
+		return zw.Close()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := make([]BundleEntry, len(sources))
+	for i, src := range sources {
+		manifest[i] = src.entry
+	}
+
+	return &BundleResult{URL: doc.URL, ExpiresAt: doc.ExpiresAt, Manifest: manifest}, nil
+}
+
+// resolveBundleItem 找到条目对应的对象：handle 和 document_id 都只能指向自己生成的文件
+func resolveBundleItem(ctx context.Context, item BundleItem) (bundleSource, error) {
+	switch {
+	case item.Content != "":
+		if item.Name == "" {
+			return bundleSource{}, errors.New("name is required for inline content")
+		}
+		return bundleSource{
+			entry:   BundleEntry{Name: safeDocumentName(item.Name), Source: "inline", Size: int64(len(item.Content))},
+			content: item.Content,
+		}, nil
+	case item.DocumentId > 0:
+		var doc dao.GeneratedDocument
+		err := client.Mysql.WithContext(ctx).Scopes(ownedDocuments(ctx)).Where("id = ?", item.DocumentId).Take(&doc).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return bundleSource{}, fmt.Errorf("document %d not found", item.DocumentId)
+		}
+		if err != nil {
+			return bundleSource{}, err
+		}
+		return statBundleSource(ctx, doc.ObjectName, "document", item.Name, doc.FileName)
+	case item.Handle != "":
+		objectName, ok := generatedObjectName(item.Handle)
+		if !ok {
+			return bundleSource{}, fmt.Errorf("handle %q is not a generated file", item.Handle)
+		}
+		// handle 中的链接不校验签名和有效期，必须能在自己生成的文件记录中找到；内容相同的文件共用一个对象，取最新一条记录的文件名
+		var doc dao.GeneratedDocument
+		err := client.Mysql.WithContext(ctx).Scopes(ownedDocuments(ctx)).
+			Where("object_name = ?", objectName).Order("id desc").Take(&doc).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return bundleSource{}, fmt.Errorf("handle %q not found in your generated files", item.Handle)
+		}
+		if err != nil {
+			return bundleSource{}, err
+		}
+		return statBundleSource(ctx, objectName, "handle", item.Name, cmp.Or(doc.FileName, path.Base(objectName)))
+	default:
+		return bundleSource{}, errors.New("one of handle, document_id or content is required")
+	}
+}
+
+func statBundleSource(ctx context.Context, objectName, source, name, defaultName string) (bundleSource, error) {
+	info, err := storage.Store.Stat(ctx, objectName)
+	if err != nil {
+		return bundleSource{}, fmt.Errorf("%s is no longer available: %w", objectName, err)
+	}
+	if name == "" {
+		name = defaultName
+	}
+
+	return bundleSource{
+		entry:      BundleEntry{Name: safeDocumentName(name), Source: source, Size: info.Size},
+		objectName: objectName,
+	}, nil
+}
+
+// uniqueEntryName 压缩包内文件重名时加上序号：report.csv、report (2).csv
+func uniqueEntryName(names map[string]int, name string) string {
+	names[name]++
+	n := names[name]
+	if n == 1 {
+		return name
+	}
+
+	ext := path.Ext(name)
+	for {
+		candidate := fmt.Sprintf("%s (%d)%s", strings.TrimSuffix(name, ext), n, ext)
+		if names[candidate] == 0 {
+			names[candidate] = 1
+			return candidate
+		}
+		n++
+	}
+}
+
+func writeBundleEntry(ctx context.Context, zw *zip.Writer, src bundleSource) error {
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: src.entry.Name, Method: zip.Deflate, Modified: time.Now()})
+	if err != nil {
+		return err
+	}
+	if src.objectName == "" {
+		_, err = io.WriteString(w, src.content)
+		return err
+	}
+
+	obj, err := storage.Store.Get(ctx, src.objectName)
+	if err != nil {
+		return err
+	}
+	defer obj.Close()
+	_, err = io.Copy(w, obj)
+
+	return err
+}
@@ -0,0 +1,14 @@
+package tools
+
+import "testing"
+
+func TestUniqueEntryName(t *testing.T) {
+	names := map[string]int{}
+	inputs := []string{"report.csv", "report.csv", "report (2).csv", "report.csv", "README", "README", "日报.pdf", "日报.pdf"}
+	want := []string{"report.csv", "report (2).csv", "report (2) (2).csv", "report (3).csv", "README", "README (2)", "日报.pdf", "日报 (2).pdf"}
+	for i, name := range inputs {
+		if got := uniqueEntryName(names, name); got != want[i] {
+			t.Errorf("uniqueEntryName(%q) #%d = %q, want %q", name, i, got, want[i])
+		}
+	}
+}
@@ -0,0 +1,302 @@
+package tools
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/golang/freetype/truetype"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/wcharczuk/go-chart/v2"
+	"html"
+	"io"
+	"log"
+	"mcp/server/config"
+	"os"
+	"sync"
+	"time"
+)
+
+func getGenerateChartTool() mcp.Tool {
+	tool := mcp.NewTool("generate_chart",
+		mcp.WithDescription(`
+根据数据系列在服务端绘制图表（折线图、柱状图、饼图），上传后返回图片下载链接。
+适合把 user_stats 等统计结果可视化。返回的 markdown 字段是现成的图片引用，可以直接写进 generate_document_link 的 markdown/html/pdf 报告里；
+PDF 报告只能嵌入 png 图片，svg 只适合网页。
+- line：labels 为横轴刻度（例如日期），可以有多个 series
+- bar：labels 为每根柱子的名称，只能有一个 series
+- pie：labels 为每个扇区的名称，只能有一个 series，values 不能为负
+`),
+		mcp.WithInputSchema[GenerateChartReq](),
+		mcp.WithOutputSchema[ChartResult](),
+	)
+	return tool
+}
+
+type ChartSeries struct {
+	Name   string    `json:"name,omitempty" jsonschema_description:"系列名称，显示在图例中"`
+	Values []float64 `json:"values" jsonschema_description:"数值，与 labels 一一对应"`
+}
+
+type GenerateChartReq struct {
+	Type     string        `json:"type" jsonschema:"enum=line,enum=bar,enum=pie" jsonschema_description:"图表类型"`
+	Title    string        `json:"title,omitempty" jsonschema_description:"图表标题"`
+	Labels   []string      `json:"labels" jsonschema_description:"横轴刻度或扇区名称"`
+	Series   []ChartSeries `json:"series" jsonschema_description:"数据系列"`
+	Format   string        `json:"format,omitempty" jsonschema:"enum=png,enum=svg" jsonschema_description:"图片格式，默认 png"`
+	Width    int           `json:"width,omitempty" jsonschema_description:"宽度像素，默认 960，范围 320-2400"`
+	Height   int           `json:"height,omitempty" jsonschema_description:"高度像素，默认 540，范围 240-1600"`
+	Filename string        `json:"filename,omitempty" jsonschema_description:"文件名（不含扩展名），默认 chart"`
+	// 嵌入报告的图表链接过期后图片就无法显示，报告需要长期保存时可以设置更长的有效期
+	ExpiryMinutes int `json:"expiry_minutes,omitempty" jsonschema_description:"图片链接有效期（分钟），不填使用服务端默认值，最长 7 天"`
+}
+
+type ChartResult struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Handle    string    `json:"handle"`
+	Markdown  string    `json:"markdown"`
+}
+
+func generateChart(ctx context.Context, request mcp.CallToolRequest, cr GenerateChartReq) (*ChartResult, error) {
+	if len(cr.Labels) == 0 || len(cr.Series) == 0 {
+		return nil, errors.New("labels and series are required")
+	}
+	for _, s := range cr.Series {
+		if len(s.Values) != len(cr.Labels) {
+			return nil, fmt.Errorf("series %q has %d values, expected %d to match labels", s.Name, len(s.Values), len(cr.Labels))
+		}
+	}
+
+	width := cr.Width
+	if width == 0 {
+		width = 960
+	}
+	height := cr.Height
+	if height == 0 {
+		height = 540
+	}
+	width = min(max(width, 320), 2400)
+	height = min(max(height, 240), 1600)
+
+	font, err := chartFont()
+	if err != nil {
+		return nil, err
+	}
+
+	alt := cmp.Or(cr.Title, cr.Filename, "chart")
+	if cr.Format == "svg" {
+		// go-chart 写 SVG 文本节点时不转义，标题、标签和系列名称中的 & < 会破坏文件或注入标签
+		cr = escapeChartText(cr)
+	}
+
+	var renderable interface {
+		Render(chart.RendererProvider, io.Writer) error
+	}
+	switch cr.Type {
+	case "line":
+		renderable = lineChart(cr, font, width, height)
+	case "bar":
+		if len(cr.Series) != 1 {
+			return nil, errors.New("bar chart supports exactly one series, use line for multiple series")
+		}
+		renderable = barChart(cr, font, width, height)
+	case "pie":
+		if len(cr.Series) != 1 {
+			return nil, errors.New("pie chart supports exactly one series")
+		}
+		for _, v := range cr.Series[0].Values {
+			if v < 0 {
+				return nil, errors.New("pie chart values must not be negative")
+			}
+		}
+		renderable = pieChart(cr, font, width, height)
+	default:
+		return nil, fmt.Errorf("unsupported chart type: %s", cr.Type)
+	}
+
+	provider, ext, mimeType := chart.PNG, ".png", "image/png"
+	if cr.Format == "svg" {
+		provider, ext, mimeType = chart.SVG, ".svg", "image/svg+xml"
+	}
+	var buf bytes.Buffer
+	if err := renderable.Render(provider, &buf); err != nil {
+		return nil, fmt.Errorf("render chart failed: %w", err)
+	}
+
+	filename := cr.Filename
+	if filename == "" {
+		filename = "chart"
+	}
+	// 图片需要在浏览器和报告中直接显示，所以用 inline；SVG 可以包含脚本，直接打开时只下载，
+	// 作为 <img> 引用时浏览器不受 attachment 影响
+	disposition := "inline"
+	if cr.Format == "svg" {
+		disposition = "attachment"
+	}
+	doc, err := uploadDocument(ctx, filename, ext, buf.Bytes(), mimeType, disposition, linkExpiry(cr.ExpiryMinutes))
+	if err != nil {
+		return nil, err
+	}
+
+	return &ChartResult{
+		URL:       doc.URL,
+		ExpiresAt: doc.ExpiresAt,
+		Handle:    doc.ObjectName,
+		Markdown:  fmt.Sprintf("![%s](%s)", alt, doc.URL),
+	}, nil
+}
+
+// escapeChartText 对图表中所有由调用方传入的文本做 XML 转义
+func escapeChartText(cr GenerateChartReq) GenerateChartReq {
+	cr.Title = html.EscapeString(cr.Title)
+	labels := make([]string, len(cr.Labels))
+	for i, label := range cr.Labels {
+		labels[i] = html.EscapeString(label)
+	}
+	cr.Labels = labels
+	series := make([]ChartSeries, len(cr.Series))
+	for i, s := range cr.Series {
+		series[i] = ChartSeries{Name: html.EscapeString(s.Name), Values: s.Values}
+	}
+	cr.Series = series
+
+	return cr
+}
+
+var (
+	chartFontMu     sync.Mutex
+	chartFontCached *truetype.Font
+)
+
+// chartFont 读取配置中的中文字体，未配置时使用 go-chart 自带的英文字体。
+// 只缓存读取成功的字体，字体文件补上后无需重启
+func chartFont() (*truetype.Font, error) {
+	cfg := config.Cfg.Report
+	if cfg == nil || cfg.FontPath == "" {
+		return chart.GetDefaultFont()
+	}
+
+	chartFontMu.Lock()
+	defer chartFontMu.Unlock()
+	if chartFontCached != nil {
+		return chartFontCached, nil
+	}
+	data, err := os.ReadFile(cfg.FontPath)
+	if err != nil {
+		return nil, fmt.Errorf("read font failed: %w", err)
+	}
+	font, err := truetype.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	chartFontCached = font
+
+	return font, nil
+}
+
+// CheckReportFonts 启动时检查配置的中文字体是否存在。字体不随代码分发，缺失时 PDF 报告和图表会失败，
+// 其他工具不受影响，因此只打印警告
+func CheckReportFonts() {
+	cfg := config.Cfg.Report
+	if cfg == nil {
+		return
+	}
+	for _, path := range []string{cfg.FontPath, cfg.BoldFontPath} {
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			log.Printf("[report] font %s is not available, PDF reports and charts will fail until it is installed "+
+				"(download NotoSansSC-Regular.ttf / NotoSansSC-Bold.ttf from https://fonts.google.com/noto/specimen/Noto+Sans+SC): %v", path, err)
+		}
+	}
+}
+
+// maxAxisTicks 横轴最多显示的刻度数，标签过多时按间隔抽取
+const maxAxisTicks = 12
+
+func lineChart(cr GenerateChartReq, font *truetype.Font, width, height int) *chart.Chart {
+	xs := make([]float64, len(cr.Labels))
+	for i := range xs {
+		xs[i] = float64(i)
+	}
+
+	step := (len(cr.Labels) + maxAxisTicks - 1) / maxAxisTicks
+	var ticks []chart.Tick
+	for i := 0; i < len(cr.Labels); i += step {
+		ticks = append(ticks, chart.Tick{Value: float64(i), Label: cr.Labels[i]})
+	}
+
+	series := make([]chart.Series, 0, len(cr.Series))
+	for _, s := range cr.Series {
+		series = append(series, chart.ContinuousSeries{Name: s.Name, XValues: xs, YValues: s.Values})
+	}
+
+	graph := &chart.Chart{
+		Title:  cr.Title,
+		Width:  width,
+		Height: height,
+		Font:   font,
+		Background: chart.Style{
+			Padding: chart.Box{Top: 40, Left: 20, Right: 20, Bottom: 20},
+		},
+		XAxis:  chart.XAxis{Ticks: ticks},
+		YAxis:  chart.YAxis{ValueFormatter: chartValueFormatter},
+		Series: series,
+	}
+	// 只有一个未命名系列时不需要图例
+	if len(cr.Series) > 1 || cr.Series[0].Name != "" {
+		graph.Elements = []chart.Renderable{chart.LegendThin(graph)}
+	}
+
+	return graph
+}
+
+func barChart(cr GenerateChartReq, font *truetype.Font, width, height int) *chart.BarChart {
+	bars := make([]chart.Value, len(cr.Labels))
+	for i, label := range cr.Labels {
+		bars[i] = chart.Value{Label: label, Value: cr.Series[0].Values[i]}
+	}
+
+	return &chart.BarChart{
+		Title:  cr.Title,
+		Width:  width,
+		Height: height,
+		Font:   font,
+		Background: chart.Style{
+			Padding: chart.Box{Top: 40},
+		},
+		BarWidth: max(8, min(60, (width-120)/len(bars)*2/3)),
+		YAxis:    chart.YAxis{ValueFormatter: chartValueFormatter},
+		Bars:     bars,
+	}
+}
+
+func pieChart(cr GenerateChartReq, font *truetype.Font, width, height int) *chart.PieChart {
+	values := make([]chart.Value, len(cr.Labels))
+	for i, label := range cr.Labels {
+		values[i] = chart.Value{Label: label, Value: cr.Series[0].Values[i]}
+	}
+
+	return &chart.PieChart{
+		Title:  cr.Title,
+		Width:  width,
+		Height: height,
+		Font:   font,
+		Values: values,
+	}
+}
+
+// chartValueFormatter 纵轴数值去掉多余的小数位
+func chartValueFormatter(v any) string {
+	if f, ok := v.(float64); ok {
+		if f == float64(int64(f)) {
+			return fmt.Sprintf("%d", int64(f))
+		}
+		return fmt.Sprintf("%.2f", f)
+	}
+
+	return fmt.Sprint(v)
+}
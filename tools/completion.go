@@ -0,0 +1,162 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"mcp/server/client"
+	"mcp/server/dao"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxCompletionValues MCP 规定一次最多返回 100 个补全值
+const maxCompletionValues = 100
+
+// maxDistinctValues 每列缓存的候选值数量，按出现次数从多到少取
+const maxDistinctValues = 2000
+
+// 提示词参数的固定取值，提示词处理和补全共用
+var (
+	digestFormats      = []string{"markdown", "html", "pdf"}
+	statsGranularities = []string{"day", "week", "month"}
+	statsGroupBy       = []string{"platform_name", "xgb_channel"}
+	booleanChoices     = []string{"true", "false"}
+)
+
+// promptCompletions 为本服务定义的提示词参数提供补全：栏目名称、资讯来源和作者、排序字段以及固定取值
+type promptCompletions struct{}
+
+func NewPromptCompletionProvider() server.PromptCompletionProvider {
+	return &promptCompletions{}
+}
+
+func (p *promptCompletions) CompletePromptArgument(ctx context.Context, promptName string, argument mcp.CompleteArgument, cc mcp.CompleteContext) (*mcp.Completion, error) {
+	values, err := promptArgumentValues(ctx, promptName, argument.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	return completeValues(values, argument.Value), nil
+}
+
+// promptArgumentValues 先按提示词区分，配置中的报告模板可能有同名但含义不同的参数，不提供补全
+func promptArgumentValues(ctx context.Context, promptName, argName string) ([]string, error) {
+	switch promptName {
+	case "daily_news_digest":
+		switch argName {
+		case "source":
+			return distinctContentValues(ctx, "source")
+		case "author":
+			return distinctContentValues(ctx, "display_author")
+		case "order_by":
+			return sortFields(&dao.ContentMessage{}), nil
+		case "format":
+			return digestFormats, nil
+		case "focus_only":
+			return booleanChoices, nil
+		}
+	case "benefit_claim_audit":
+		if argName == "subject" {
+			var names []string
+			for _, s := range subjectCatalog() {
+				names = append(names, s.Name)
+				names = append(names, s.Aliases...)
+			}
+			return names, nil
+		}
+	case "user_growth_report":
+		switch argName {
+		case "granularity":
+			return statsGranularities, nil
+		case "group_by":
+			return statsGroupBy, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// completeValues 先返回前缀匹配的值，再返回包含输入的值（栏目名称常带书名号），忽略大小写并去重
+func completeValues(values []string, input string) *mcp.Completion {
+	input = strings.ToLower(strings.TrimSpace(input))
+	seen := make(map[string]bool, len(values))
+	var prefix, contains []string
+	for _, v := range values {
+		if v == "" || seen[v] {
+			continue
+		}
+		seen[v] = true
+		lower := strings.ToLower(v)
+		switch {
+		case strings.HasPrefix(lower, input):
+			prefix = append(prefix, v)
+		case strings.Contains(lower, input):
+			contains = append(contains, v)
+		}
+	}
+
+	matched := append(prefix, contains...)
+	completion := &mcp.Completion{Values: matched, Total: len(matched)}
+	if len(matched) > maxCompletionValues {
+		completion.Values = matched[:maxCompletionValues]
+		completion.HasMore = true
+	}
+	if completion.Values == nil {
+		completion.Values = []string{}
+	}
+
+	return completion
+}
+
+func sortFields(model any) []string {
+	ts, err := tableSchema(queryableTable{model: model})
+	if err != nil {
+		return nil
+	}
+
+	return ts.SortFields
+}
+
+type distinctValues struct {
+	mu       sync.Mutex
+	values   []string
+	loadedAt time.Time
+}
+
+// contentValueCache 资讯表中可补全的列，结果缓存 10 分钟
+var contentValueCache = map[string]*distinctValues{
+	"source":         {},
+	"display_author": {},
+}
+
+// distinctContentValues 取最近一年资讯中该列的不同取值，按出现次数从多到少排列
+func distinctContentValues(ctx context.Context, column string) ([]string, error) {
+	cache, ok := contentValueCache[column]
+	if !ok {
+		return nil, fmt.Errorf("column %s does not support completion", column)
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if cache.values != nil && time.Since(cache.loadedAt) < 10*time.Minute {
+		return cache.values, nil
+	}
+
+	values := []string{}
+	err := client.Mysql.WithContext(ctx).Model(&dao.ContentMessage{}).
+		Where("created_at >= ?", time.Now().AddDate(-1, 0, 0).UTC()).
+		Where(column+" <> ''").
+		Group(column).
+		Order("COUNT(*) desc").
+		Limit(maxDistinctValues).
+		Pluck(column, &values).Error
+	if err != nil {
+		return nil, err
+	}
+	cache.values, cache.loadedAt = values, time.Now()
+
+	return values, nil
+}
@@ -0,0 +1,39 @@
+package tools
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestCompleteValues(t *testing.T) {
+	values := []string{"《脱水研报》", "早知道", "Alpha", "alpha", "Beta Alpha", "", "早知道"}
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"", []string{"《脱水研报》", "早知道", "Alpha", "alpha", "Beta Alpha"}},
+		{"脱水", []string{"《脱水研报》"}},
+		{"早", []string{"早知道"}},
+		{" ALP ", []string{"Alpha", "alpha", "Beta Alpha"}},
+		{"gamma", []string{}},
+	}
+	for _, tt := range tests {
+		got := completeValues(values, tt.input)
+		if !slices.Equal(got.Values, tt.want) || got.Total != len(tt.want) || got.HasMore {
+			t.Errorf("completeValues(%q) = %v (total %d, more %v), want %v", tt.input, got.Values, got.Total, got.HasMore, tt.want)
+		}
+	}
+}
+
+func TestCompleteValuesLimit(t *testing.T) {
+	var values []string
+	for i := range maxCompletionValues + 20 {
+		values = append(values, fmt.Sprintf("v%03d", i))
+	}
+
+	got := completeValues(values, "v")
+	if len(got.Values) != maxCompletionValues || got.Total != len(values) || !got.HasMore {
+		t.Errorf("completeValues returned %d values (total %d, more %v)", len(got.Values), got.Total, got.HasMore)
+	}
+}
@@ -0,0 +1,164 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/PuerkitoBio/goquery"
+	"github.com/mark3labs/mcp-go/mcp"
+	"gorm.io/gorm"
+	"mcp/server/client"
+	"mcp/server/dao"
+	"mcp/server/util"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	articleURIPrefix = "article://"
+	newsURIPrefix    = "news://"
+)
+
+func getArticleResourceTemplate() mcp.ResourceTemplate {
+	return mcp.NewResourceTemplate(articleURIPrefix+"{id}", "文章全文",
+		mcp.WithTemplateDescription("研报文章的摘要和正文纯文本，id 为 search_articles 返回的文章 ID"),
+		mcp.WithTemplateMIMEType("text/markdown"),
+	)
+}
+
+func getNewsResourceTemplate() mcp.ResourceTemplate {
+	return mcp.NewResourceTemplate(newsURIPrefix+"{id}", "资讯详情",
+		mcp.WithTemplateDescription("一条资讯（content_messages）的标题、来源、作者、时间和正文纯文本，id 为 search_content_messages 返回的 id"),
+		mcp.WithTemplateMIMEType("application/json"),
+	)
+}
+
+// resourceId 取出 URI 模板中的 {id}
+func resourceId(request mcp.ReadResourceRequest) string {
+	if values, ok := request.Params.Arguments["id"].([]string); ok && len(values) > 0 {
+		return strings.TrimSpace(values[0])
+	}
+
+	return ""
+}
+
+func readArticleResource(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	id := resourceId(request)
+	if id == "" {
+		return nil, errors.New("article id is required")
+	}
+
+	summary, err := dao.GetArticleSummary(id)
+	if err != nil {
+		return nil, err
+	}
+	content, err := dao.GetFullContentByID(id)
+	if err != nil {
+		return nil, err
+	}
+	content = strings.TrimSpace(content)
+	if summary == "" && content == "" {
+		return nil, fmt.Errorf("article %s not found", id)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# 文章 %s\n\n", id)
+	if summary != "" {
+		fmt.Fprintf(&sb, "## 摘要\n\n%s\n\n", strings.TrimSpace(summary))
+	}
+	fmt.Fprintf(&sb, "## 正文\n\n%s\n", content)
+
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{URI: request.Params.URI, MIMEType: "text/markdown", Text: sb.String()},
+	}, nil
+}
+
+type NewsResource struct {
+	Id            int64  `json:"id"`
+	Title         string `json:"title"`
+	SubTitle      string `json:"sub_title,omitempty"`
+	Summary       string `json:"summary,omitempty"`
+	Source        string `json:"source,omitempty"`
+	DisplayAuthor string `json:"display_author,omitempty"`
+	Url           string `json:"url,omitempty"`
+	IsPremium     bool   `json:"is_premium"`
+	IsTodaysFocus bool   `json:"is_todays_focus"`
+	CreatedAt     string `json:"created_at"`
+	Content       string `json:"content,omitempty"`
+}
+
+func readNewsResource(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	id, err := strconv.ParseInt(resourceId(request), 10, 64)
+	if err != nil || id <= 0 {
+		return nil, fmt.Errorf("invalid news id %q", resourceId(request))
+	}
+
+	var msg dao.ContentMessage
+	err = client.Mysql.WithContext(ctx).
+		Where("id = ? AND is_deleted = ? AND is_withdrawn = ?", id, false, false).
+		Take(&msg).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("news %d not found", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	news := toNewsResource(msg)
+	news.Content = htmlText(msg.Content)
+	data, err := json.Marshal(news)
+	if err != nil {
+		return nil, err
+	}
+
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{URI: request.Params.URI, MIMEType: "application/json", Text: string(data)},
+	}, nil
+}
+
+// toNewsResource 不含正文，需要正文时由调用方填充
+func toNewsResource(msg dao.ContentMessage) NewsResource {
+	return NewsResource{
+		Id:            msg.Id,
+		Title:         msg.Title,
+		SubTitle:      msg.SubTitle,
+		Summary:       msg.Summary,
+		Source:        msg.Source,
+		DisplayAuthor: msg.DisplayAuthor,
+		Url:           msg.Url,
+		IsPremium:     msg.IsPremium,
+		IsTodaysFocus: msg.IsTodaysFocus,
+		CreatedAt:     msg.CreatedAt.In(util.Loc).Format(time.DateTime),
+	}
+}
+
+// htmlText 去掉正文中的 HTML 标签，解析失败时原样返回
+func htmlText(html string) string {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return html
+	}
+
+	return strings.TrimSpace(doc.Text())
+}
+
+// articleLinks 检索结果附带的文章资源链接，客户端可以直接把文章加入对话
+func articleLinks(ids ...string) []mcp.Content {
+	links := make([]mcp.Content, 0, len(ids))
+	for _, id := range ids {
+		links = append(links, mcp.NewResourceLink(articleURIPrefix+id, "文章 "+id, "", "text/markdown"))
+	}
+
+	return links
+}
+
+func newsLinks(messages []dao.ContentMessage) []mcp.Content {
+	links := make([]mcp.Content, 0, len(messages))
+	for _, msg := range messages {
+		links = append(links, mcp.NewResourceLink(newsURIPrefix+strconv.FormatInt(msg.Id, 10), msg.Title, msg.Summary, "application/json"))
+	}
+
+	return links
+}
@@ -1,15 +1,20 @@
 package tools
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"github.com/mark3labs/mcp-go/mcp"
-	"github.com/minio/minio-go/v7"
-	"mcp/server/client"
+	"io"
 	"mcp/server/config"
-	"net/url"
+	"mcp/server/storage"
+	"mcp/server/util"
+	"path"
 	"strings"
 	"time"
+	"unicode"
 )
 
 func generateCsvTool() mcp.Tool {
@@ -19,84 +24,273 @@ func generateCsvTool() mcp.Tool {
 
 		// 参数定义
 		mcp.WithString("filename", mcp.Required(), mcp.Description("文件名 (不包含扩展名)，例如 'daily_summary'")),
-		mcp.WithString("file_type", mcp.Required(), mcp.Description("文件类型: 'markdown' (用于文本报告), 'csv' (用于表格数据), 'json'")),
+		mcp.WithString("file_type", mcp.Required(), mcp.Description("文件类型: 'markdown' (用于文本报告), 'csv' (用于表格数据), 'json', 'html' / 'pdf' (把 markdown 内容渲染成网页或 PDF 报告，方便直接阅读), 'xlsx' (Excel 表格，content 传 CSV 文本或 JSON 数组，业务人员用 Excel 打开时优先使用)")),
 		mcp.WithString("content", mcp.Required(), mcp.Description("要保存到文件中的完整文本内容")),
+		mcp.WithBoolean("auto_repair", mcp.Description("csv/json/xlsx 内容校验失败时是否自动修复（去掉代码块标记、补齐列数不一致的行、去掉 JSON 尾逗号等），默认 false，校验失败会返回出错的行号和列号")),
+		mcp.WithBoolean("utf8_bom", mcp.Description("仅对 csv 生效：是否在文件开头加 UTF-8 BOM，默认 false；文件要用 Excel 打开且包含中文时设为 true，避免乱码")),
+		mcp.WithNumber("expiry_minutes", mcp.Description("下载链接有效期（分钟），不填使用服务端默认值，最长 7 天")),
 	)
 	return tool
 }
 
 type GenerateFileReq struct {
-	FileName string `json:"filename"`
-	FileType string `json:"file_type"`
-	Content  string `json:"content"`
+	FileName   string `json:"filename"`
+	FileType   string `json:"file_type"`
+	Content    string `json:"content"`
+	UTF8BOM    bool   `json:"utf8_bom"`
+	AutoRepair bool   `json:"auto_repair"`
+	// 下载链接有效期（分钟），0 表示使用配置的默认值
+	ExpiryMinutes int `json:"expiry_minutes"`
 }
 
 func generateCsv(ctx context.Context, request mcp.CallToolRequest, gr GenerateFileReq) (*mcp.CallToolResult, error) {
+	if gr.FileName == "" || gr.Content == "" {
+		return mcp.NewToolResultError("Filename and content are required"), nil
+	}
+
+	doc, fixes, err := generateDocument(ctx, gr)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	// 返回给 LLM
+	expires := doc.ExpiresAt.In(util.Loc).Format(time.DateTime)
+	if len(fixes) > 0 {
+		return mcp.NewToolResultText(fmt.Sprintf("文件已生成（已自动修复: %s）。下载链接（%s 前有效）: %s", strings.Join(fixes, "; "), expires, doc.URL)), nil
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("文件已生成。下载链接（%s 前有效）: %s", expires, doc.URL)), nil
+
+}
+
+// generateDocument 校验、转换并上传文件，返回下载链接和自动修复说明，render_report 也通过它生成文件
+func generateDocument(ctx context.Context, gr GenerateFileReq) (*StoredDocument, []string, error) {
 	// A. 解析参数
 	filename := gr.FileName
 	fileType := gr.FileType
-	content := gr.Content
 
-	if filename == "" || content == "" {
-		return mcp.NewToolResultError("Filename and content are required"), nil
+	// 上传前按文件类型校验内容，失败时返回具体位置让模型修正后重试
+	content, fixes, err := validateContent(fileType, gr.Content, gr.AutoRepair)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s content is invalid: %v. Fix the content and retry, or set auto_repair=true", fileType, err)
 	}
 
 	// B. 处理后缀和 MIME type
+	// html、pdf 在浏览器中直接打开，其余类型强制下载
 	var ext, mimeType string
+	disposition := "attachment"
 	switch fileType {
 	case "csv":
 		ext = ".csv"
-		mimeType = "text/csv"
+		mimeType = "text/csv; charset=utf-8"
+		if gr.UTF8BOM {
+			content = withUTF8BOM(content)
+		}
+	case "xlsx":
+		ext = ".xlsx"
+		mimeType = xlsxMimeType
+		header, rows, err := parseTable(content)
+		if err != nil {
+			return nil, nil, fmt.Errorf("xlsx content must be CSV or a JSON array: %v", err)
+		}
+		data, err := buildXlsx(header, rows)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Build xlsx failed: %v", err)
+		}
+		content = string(data)
 	case "json":
 		ext = ".json"
 		mimeType = "application/json"
+	case "html":
+		ext = ".html"
+		mimeType = "text/html; charset=utf-8"
+		disposition = "inline"
+		data, err := renderMarkdownHTML(ctx, filename, content)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Render html failed: %v", err)
+		}
+		content = string(data)
+	case "pdf":
+		ext = ".pdf"
+		mimeType = "application/pdf"
+		disposition = "inline"
+		data, err := renderMarkdownPDF(ctx, filename, content)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Render pdf failed: %v", err)
+		}
+		content = string(data)
 	default:
 		ext = ".md"
 		mimeType = "text/markdown"
 	}
 
-	// C. 构建完整文件名
-	// 加上时间戳防止重名: market_report_170123456.md
-	fullObjectName := fmt.Sprintf("generated/%s_%d%s", filename, time.Now().Unix(), ext)
-
-	// D. 上传到 MinIO (调用上一轮封装好的 Upload 方法)
+	// C. 上传到对象存储，对象名在 uploadDocument 中根据清理后的文件名生成
 	// 注意：这里我们直接把 content 字符串转为 byte 数组上传，不需要存本地文件
-	preUrl, err := UploadStringContentToMinIO(ctx, fullObjectName, content, mimeType)
+	doc, err := uploadDocument(ctx, filename, ext, []byte(content), mimeType, disposition, linkExpiry(gr.ExpiryMinutes))
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Upload failed: %v", err)), nil
+		return nil, nil, fmt.Errorf("Upload failed: %v", err)
+	}
+
+	return doc, fixes, nil
+}
+
+// validateContent 校验 csv、json 以及 xlsx 的源数据，autoRepair 时尝试修复并返回修复说明
+func validateContent(fileType, content string, autoRepair bool) (string, []string, error) {
+	probe, _ := stripCodeFence(content)
+	isJSON := fileType == "json" || (fileType == "xlsx" && strings.HasPrefix(strings.TrimSpace(probe), "["))
+	isCSV := fileType == "csv" || (fileType == "xlsx" && !isJSON)
+
+	var err error
+	switch {
+	case isJSON:
+		err = validateJSON(content)
+	case isCSV:
+		err = validateCSV(content)
+	default:
+		return content, nil, nil
+	}
+	if err == nil || !autoRepair {
+		return content, nil, err
 	}
 
-	// E. 返回给 LLM
-	return mcp.NewToolResultText(fmt.Sprintf("文件已生成。下载链接: %s", preUrl)), nil
+	var fixes []string
+	if isJSON {
+		content, fixes = repairJSON(content)
+		err = validateJSON(content)
+	} else {
+		if content, fixes, err = repairCSV(content); err == nil {
+			err = validateCSV(content)
+		}
+	}
+	if err != nil {
+		return "", nil, err
+	}
 
+	return content, fixes, nil
 }
 
 // 辅助函数：直接上传字符串内容
 func UploadStringContentToMinIO(ctx context.Context, objectName, content, contentType string) (string, error) {
-	BucketName := config.Cfg.MinIO.BucketName
 	reader := strings.NewReader(content)
-	// 3. 上传到 MinIO
-	// 使用 PutObject 直接上传内存流，不需要存本地磁盘
-	_, err := client.MinIO.PutObject(ctx, BucketName, objectName, reader, int64(reader.Len()), minio.PutObjectOptions{
-		ContentType: contentType,
+	return UploadContentToMinIO(ctx, objectName, reader, int64(reader.Len()), contentType)
+}
+
+// UploadContentToMinIO 上传任意内容（包括 xlsx 等二进制文件）并返回预签名下载链接。
+// objectName 只取文件名部分，文件统一存放在 generated/ 下；reader 以流的方式上传，不会整个读入内存
+func UploadContentToMinIO(ctx context.Context, objectName string, reader io.Reader, size int64, contentType string) (string, error) {
+	ext := path.Ext(objectName)
+	doc, err := writeDocument(ctx, strings.TrimSuffix(path.Base(objectName), ext), ext, contentType, "attachment", "", linkExpiry(0), func(w io.Writer) error {
+		_, err := io.Copy(w, reader)
+		return err
 	})
 	if err != nil {
 		return "", err
 	}
 
-	// 4. 生成预签名下载链接 (Presigned URL)
-	// 有效期设为 1 小时
-	expiry := time.Hour * 1
+	return doc.URL, nil
+}
+
+type StoredDocument struct {
+	ObjectName string
+	Name       string
+	URL        string
+	ExpiresAt  time.Time
+}
+
+// uploadDocument 上传生成的文件并返回预签名下载链接。
+// filename 由模型传入，清理后才用于对象名和 Content-Disposition；内容与之前生成的文件完全相同时直接复用已有对象。
+// disposition 为 attachment 时浏览器强制下载，为 inline 时直接打开
+func uploadDocument(ctx context.Context, filename, ext string, data []byte, contentType, disposition string, expiry time.Duration) (*StoredDocument, error) {
+	base := safeDocumentName(filename)
+	name := base + ext
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
 
-	// 设置响应头：让浏览器强制下载，而不是在页面里打开
-	reqParams := make(url.Values)
-	reqParams.Set("response-content-disposition", fmt.Sprintf("attachment; filename=\"%s\"", objectName))
+	objectName, ok := findDuplicateDocument(ctx, hash, contentType)
+	if !ok {
+		// 加上时间戳和内容摘要防止重名: market_report_170123456_1a2b3c4d.md
+		objectName = fmt.Sprintf("%s%s_%d_%s%s", generatedPrefix, base, time.Now().Unix(), hash[:8], ext)
+		// 使用 PutObject 直接上传内存流，不需要存本地磁盘
+		_, err := storage.Store.Put(ctx, objectName, bytes.NewReader(data), int64(len(data)), storage.PutOptions{
+			ContentType:        contentType,
+			ContentDisposition: contentDisposition(disposition, name),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	recordDocument(ctx, objectName, name, hash, contentType, disposition, int64(len(data)))
 
-	presignedURL, err := client.MinIO.PresignedGetObject(ctx, BucketName, objectName, expiry, reqParams)
+	link, err := presignDocument(ctx, objectName, name, disposition, expiry)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	return &StoredDocument{ObjectName: objectName, Name: name, URL: link, ExpiresAt: time.Now().Add(expiry)}, nil
+}
+
+// presignDocument 为已上传的文件生成预签名下载链接，重新签发链接时也使用它
+func presignDocument(ctx context.Context, objectName, name, disposition string, expiry time.Duration) (string, error) {
+	// 设置响应头：attachment 让浏览器强制下载，inline 在页面里打开；
+	// 复用的对象上传时的文件名可能不同，以本次的文件名为准
+	return storage.Store.PresignGet(ctx, objectName, expiry, contentDisposition(disposition, name))
+}
+
+// linkExpiry 返回下载链接有效期：minutes 为 0 时使用配置的默认值，并限制在配置的最大值以内
+func linkExpiry(minutes int) time.Duration {
+	cfg := config.DocumentConfig{LinkExpiryMinutes: 60, MaxLinkExpiryMinutes: 7 * 24 * 60}
+	if c := config.Cfg.Document; c != nil {
+		if c.LinkExpiryMinutes > 0 {
+			cfg.LinkExpiryMinutes = c.LinkExpiryMinutes
+		}
+		// 预签名链接最长 7 天
+		if c.MaxLinkExpiryMinutes > 0 {
+			cfg.MaxLinkExpiryMinutes = min(c.MaxLinkExpiryMinutes, cfg.MaxLinkExpiryMinutes)
+		}
+	}
+	if minutes <= 0 {
+		minutes = cfg.LinkExpiryMinutes
+	}
+
+	return time.Duration(min(minutes, cfg.MaxLinkExpiryMinutes)) * time.Minute
+}
+
+// safeDocumentName 清理模型传入的文件名：去掉路径和扩展名以外的特殊字符，限制长度，防止路径穿越和响应头注入
+func safeDocumentName(filename string) string {
+	name := strings.Trim(safeFileName(filename), " .")
+	name = strings.Map(func(r rune) rune {
+		if r == '\\' || r == '/' || unicode.IsControl(r) {
+			return '_'
+		}
+		return r
+	}, name)
+	if runes := []rune(name); len(runes) > 80 {
+		name = string(runes[:80])
+	}
+	if name == "" {
+		return "document"
+	}
+
+	return name
+}
+
+// contentDisposition 按 RFC 6266 生成响应头：filename 是只含 ASCII 的兜底文件名，
+// filename* 按 RFC 5987 携带 UTF-8 编码的完整文件名，中文文件名在浏览器中才能正确显示
+func contentDisposition(disposition, name string) string {
+	fallback := strings.Map(func(r rune) rune {
+		if r < 0x20 || r > 0x7e || r == '"' || r == '\\' {
+			return '_'
+		}
+		return r
+	}, name)
+
+	var encoded strings.Builder
+	for _, c := range []byte(name) {
+		if 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || strings.IndexByte("!#$&+-.^_`|~", c) >= 0 {
+			encoded.WriteByte(c)
+		} else {
+			fmt.Fprintf(&encoded, "%%%02X", c)
+		}
 	}
 
-	// 参考上一轮的代码实现
-	return presignedURL.String(), nil
+	return fmt.Sprintf("%s; filename=\"%s\"; filename*=UTF-8''%s", disposition, fallback, encoded.String())
 }
@@ -0,0 +1,47 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestContentDisposition(t *testing.T) {
+	tests := []struct {
+		disposition string
+		name        string
+		want        string
+	}{
+		{"attachment", "report.csv", `attachment; filename="report.csv"; filename*=UTF-8''report.csv`},
+		{"inline", "日报.pdf", `inline; filename="__.pdf"; filename*=UTF-8''%E6%97%A5%E6%8A%A5.pdf`},
+		{"attachment", `a"b\c.txt`, `attachment; filename="a_b_c.txt"; filename*=UTF-8''a%22b%5Cc.txt`},
+		{"attachment", "a b;c.csv", `attachment; filename="a b;c.csv"; filename*=UTF-8''a%20b%3Bc.csv`},
+		{"attachment", "line\r\nbreak.md", `attachment; filename="line__break.md"; filename*=UTF-8''line%0D%0Abreak.md`},
+	}
+	for _, tt := range tests {
+		if got := contentDisposition(tt.disposition, tt.name); got != tt.want {
+			t.Errorf("contentDisposition(%q, %q) = %s, want %s", tt.disposition, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSafeDocumentName(t *testing.T) {
+	tests := []struct {
+		filename string
+		want     string
+	}{
+		{"market_report", "market_report"},
+		{"../../etc/passwd", "passwd"},
+		{`..\..\secret`, "secret"},
+		{"a:b*c?", "a_b_c_"},
+		{"  .hidden. ", "hidden"},
+		{"", "document"},
+		{"..", "document"},
+		{"tab\there", "tab_here"},
+		{strings.Repeat("长", 100), strings.Repeat("长", 80)},
+	}
+	for _, tt := range tests {
+		if got := safeDocumentName(tt.filename); got != tt.want {
+			t.Errorf("safeDocumentName(%q) = %q, want %q", tt.filename, got, tt.want)
+		}
+	}
+}
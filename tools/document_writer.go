@@ -0,0 +1,178 @@
+package tools
+
+import (
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+	"mcp/server/storage"
+	"sync"
+	"time"
+)
+
+// DocumentWriter 以流的方式写入生成的文件：写入的数据经过管道直接上传到对象存储（MinIO 按分片上传），
+// 不需要把整个文件放在内存中。写完后调用 Close 取得下载链接，中途出错时调用 Abort 放弃上传。
+type DocumentWriter struct {
+	ctx         context.Context
+	pipe        *io.PipeWriter
+	w           io.Writer
+	compressor  io.Closer
+	hash        hash.Hash
+	size        int64
+	done        chan error
+	once        sync.Once
+	err         error
+	objectName  string
+	name        string
+	contentType string
+	disposition string
+	expiry      time.Duration
+}
+
+// newDocumentWriter 创建流式写入的文件。compression 为 gzip 时整个文件压缩为 .gz，
+// 为 zip 时把文件作为唯一条目打包为 .zip，压缩后的文件总是以附件方式下载
+func newDocumentWriter(ctx context.Context, filename, ext, contentType, disposition, compression string, expiry time.Duration) (*DocumentWriter, error) {
+	base := safeDocumentName(filename)
+	name := base + ext
+	switch compression {
+	case "gzip":
+		name, contentType, disposition = name+".gz", "application/gzip", "attachment"
+	case "zip":
+		name, contentType, disposition = base+".zip", "application/zip", "attachment"
+	case "", "none":
+	default:
+		return nil, fmt.Errorf("unsupported compression: %s", compression)
+	}
+
+	// 写完之前不知道内容摘要，对象名用随机后缀防止重名
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+	objectName := fmt.Sprintf("%s%s_%d_%s%s", generatedPrefix, base, time.Now().Unix(), hex.EncodeToString(suffix), name[len(base):])
+
+	pr, pw := io.Pipe()
+	dw := &DocumentWriter{
+		ctx:         ctx,
+		pipe:        pw,
+		hash:        sha256.New(),
+		done:        make(chan error, 1),
+		objectName:  objectName,
+		name:        name,
+		contentType: contentType,
+		disposition: disposition,
+		expiry:      expiry,
+	}
+	go func() {
+		_, err := storage.Store.Put(ctx, objectName, pr, -1, storage.PutOptions{
+			ContentType:        contentType,
+			ContentDisposition: contentDisposition(disposition, name),
+		})
+		// 上传失败时让后续的 Write 立即返回错误
+		pr.CloseWithError(err)
+		dw.done <- err
+	}()
+
+	// 摘要和大小按实际存储的（压缩后的）字节计算，与 uploadDocument 的去重方式一致
+	dw.w = io.MultiWriter(pw, dw.hash, sizeCounter{&dw.size})
+	switch compression {
+	case "gzip":
+		gz := gzip.NewWriter(dw.w)
+		gz.Name = base + ext
+		dw.w, dw.compressor = gz, gz
+	case "zip":
+		zw := zip.NewWriter(dw.w)
+		entry, err := zw.CreateHeader(&zip.FileHeader{Name: base + ext, Method: zip.Deflate, Modified: time.Now()})
+		if err != nil {
+			dw.Abort(err)
+			return nil, err
+		}
+		dw.w, dw.compressor = entry, zw
+	}
+
+	return dw, nil
+}
+
+func (dw *DocumentWriter) Write(p []byte) (int, error) {
+	return dw.w.Write(p)
+}
+
+// Close 结束写入，等待上传完成后登记文件并返回下载链接。
+// 内容与之前生成的文件完全相同时删除刚上传的对象，复用已有对象
+func (dw *DocumentWriter) Close() (*StoredDocument, error) {
+	var err error
+	if dw.compressor != nil {
+		err = dw.compressor.Close()
+	}
+	if err != nil {
+		dw.Abort(err)
+		return nil, err
+	}
+	_ = dw.pipe.Close()
+	if err := dw.wait(); err != nil {
+		return nil, err
+	}
+
+	objectName := dw.objectName
+	hash := hex.EncodeToString(dw.hash.Sum(nil))
+	if existing, ok := findDuplicateDocument(dw.ctx, hash, dw.contentType); ok && existing != objectName {
+		if err := storage.Store.Remove(dw.ctx, objectName); err != nil {
+			log.Printf("[documents] remove duplicate %s failed: %v", objectName, err)
+		}
+		objectName = existing
+	}
+	recordDocument(dw.ctx, objectName, dw.name, hash, dw.contentType, dw.disposition, dw.size)
+
+	link, err := presignDocument(dw.ctx, objectName, dw.name, dw.disposition, dw.expiry)
+	if err != nil {
+		return nil, err
+	}
+
+	return &StoredDocument{ObjectName: objectName, Name: dw.name, URL: link, ExpiresAt: time.Now().Add(dw.expiry)}, nil
+}
+
+// Abort 放弃上传，存储不会留下写了一半的对象
+func (dw *DocumentWriter) Abort(err error) {
+	if err == nil {
+		err = errors.New("upload aborted")
+	}
+	dw.pipe.CloseWithError(err)
+	_ = dw.wait()
+}
+
+// wait 等待后台上传结束，Close 和 Abort 可以先后调用
+func (dw *DocumentWriter) wait() error {
+	dw.once.Do(func() {
+		dw.err = <-dw.done
+	})
+
+	return dw.err
+}
+
+// writeDocument 用 write 生成文件内容并流式上传，write 返回错误时放弃上传
+func writeDocument(ctx context.Context, filename, ext, contentType, disposition, compression string, expiry time.Duration, write func(w io.Writer) error) (*StoredDocument, error) {
+	dw, err := newDocumentWriter(ctx, filename, ext, contentType, disposition, compression, expiry)
+	if err != nil {
+		return nil, err
+	}
+	if err := write(dw); err != nil {
+		dw.Abort(err)
+		return nil, err
+	}
+
+	return dw.Close()
+}
+
+type sizeCounter struct {
+	n *int64
+}
+
+func (c sizeCounter) Write(p []byte) (int, error) {
+	*c.n += int64(len(p))
+	return len(p), nil
+}
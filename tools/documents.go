@@ -0,0 +1,228 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"gorm.io/gorm"
+	"log"
+	"mcp/server/auth"
+	"mcp/server/client"
+	"mcp/server/dao"
+	"mcp/server/storage"
+	"path"
+	"strings"
+	"time"
+)
+
+// recordDocument 登记生成的文件；登记失败只记录日志，不影响本次下载链接
+func recordDocument(ctx context.Context, objectName, name, hash, contentType, disposition string, size int64) {
+	caller := auth.CallerFromContext(ctx)
+	doc := dao.GeneratedDocument{
+		ObjectName:  objectName,
+		ContentHash: hash,
+		FileName:    name,
+		FileType:    strings.TrimPrefix(path.Ext(name), "."),
+		ContentType: contentType,
+		Disposition: disposition,
+		Size:        size,
+		Creator:     caller.Name,
+		CreatorRole: caller.Role,
+		SessionId:   sessionId(ctx),
+	}
+	if err := client.Mysql.WithContext(ctx).Create(&doc).Error; err != nil {
+		log.Printf("[documents] record %s failed: %v", objectName, err)
+	}
+}
+
+// findDuplicateDocument 查找内容和类型都相同、且对象仍然存在的已生成文件。
+// 快到保留期限的对象不再复用，避免新链接签发后文件很快被清理
+func findDuplicateDocument(ctx context.Context, hash, contentType string) (string, bool) {
+	var doc dao.GeneratedDocument
+	err := client.Mysql.WithContext(ctx).
+		Where("content_hash = ? AND content_type = ?", hash, contentType).
+		Order("id desc").
+		Take(&doc).Error
+	if err != nil {
+		return "", false
+	}
+
+	info, err := storage.Store.Stat(ctx, doc.ObjectName)
+	if err != nil {
+		return "", false
+	}
+	if days := retentionConfig().MaxAgeDays; days > 0 && time.Since(info.LastModified) > time.Duration(days)*24*time.Hour/2 {
+		return "", false
+	}
+
+	return doc.ObjectName, true
+}
+
+func sessionId(ctx context.Context) string {
+	if session := server.ClientSessionFromContext(ctx); session != nil {
+		return session.SessionID()
+	}
+
+	return ""
+}
+
+// ownedDocuments 只能访问自己生成的文件；未识别身份的调用方共用 anonymous，只能访问当前会话中生成的文件
+func ownedDocuments(ctx context.Context) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		caller := auth.CallerFromContext(ctx)
+		db = db.Where("creator = ?", caller.Name)
+		if caller.Role == "" {
+			db = db.Where("session_id = ?", sessionId(ctx))
+		}
+		return db
+	}
+}
+
+func getListDocumentsTool() mcp.Tool {
+	tool := mcp.NewTool("list_documents",
+		mcp.WithDescription(`
+列出当前调用方之前生成的文件（报告、导出表格、图表等），按生成时间倒序。
+下载链接有有效期，用户找之前的文件或链接已过期时，先用本工具找到文件，再用 get_document_link 重新生成链接。
+`),
+		mcp.WithInputSchema[ListDocumentsReq](),
+		mcp.WithOutputSchema[DocumentList](),
+	)
+	return tool
+}
+
+type ListDocumentsReq struct {
+	FileType string `json:"file_type,omitempty" jsonschema_description:"按文件类型过滤，例如 csv、xlsx、pdf、png"`
+	Keyword  string `json:"keyword,omitempty" jsonschema_description:"按文件名模糊匹配"`
+	Limit    int    `json:"limit,omitempty" jsonschema_description:"返回数量，默认 20，最大 100"`
+}
+
+type DocumentList struct {
+	Total     int64                   `json:"total"`
+	Documents []dao.GeneratedDocument `json:"documents"`
+}
+
+func listDocuments(ctx context.Context, request mcp.CallToolRequest, lr ListDocumentsReq) (*DocumentList, error) {
+	limit := lr.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+	limit = min(limit, 100)
+
+	tx := client.Mysql.WithContext(ctx).Model(&dao.GeneratedDocument{}).Scopes(ownedDocuments(ctx))
+	if lr.FileType != "" {
+		tx = tx.Where("file_type = ?", strings.TrimPrefix(strings.ToLower(lr.FileType), "."))
+	}
+	if lr.Keyword != "" {
+		tx = tx.Where("file_name LIKE ?", "%"+strings.TrimSpace(lr.Keyword)+"%")
+	}
+
+	result := &DocumentList{Documents: []dao.GeneratedDocument{}}
+	if err := tx.Count(&result.Total).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Order("id desc").Limit(limit).Find(&result.Documents).Error; err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func getDocumentLinkTool() mcp.Tool {
+	tool := mcp.NewTool("get_document_link",
+		mcp.WithDescription("为之前生成的文件重新签发下载链接，id 来自 list_documents。可以通过 expiry_minutes 指定链接有效期。"),
+		mcp.WithInputSchema[GetDocumentLinkReq](),
+		mcp.WithOutputSchema[DocumentLink](),
+	)
+	return tool
+}
+
+type GetDocumentLinkReq struct {
+	Id            int64 `json:"id" jsonschema_description:"文件 ID"`
+	ExpiryMinutes int   `json:"expiry_minutes,omitempty" jsonschema_description:"下载链接有效期（分钟），不填使用服务端默认值，最长 7 天"`
+}
+
+type DocumentLink struct {
+	Document  dao.GeneratedDocument `json:"document"`
+	URL       string                `json:"url"`
+	ExpiresAt time.Time             `json:"expires_at"`
+}
+
+func getDocumentLink(ctx context.Context, request mcp.CallToolRequest, gr GetDocumentLinkReq) (*DocumentLink, error) {
+	var doc dao.GeneratedDocument
+	err := client.Mysql.WithContext(ctx).Scopes(ownedDocuments(ctx)).Where("id = ?", gr.Id).Take(&doc).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("document %d not found", gr.Id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := storage.Store.Stat(ctx, doc.ObjectName); err != nil {
+		return nil, fmt.Errorf("document %d is no longer available: %w", gr.Id, err)
+	}
+	expiry := linkExpiry(gr.ExpiryMinutes)
+	link, err := presignDocument(ctx, doc.ObjectName, doc.FileName, doc.Disposition, expiry)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DocumentLink{Document: doc, URL: link, ExpiresAt: time.Now().Add(expiry)}, nil
+}
+
+func getDeleteDocumentsTool() mcp.Tool {
+	tool := mcp.NewTool("delete_documents",
+		mcp.WithDescription("删除之前生成的文件，没有其他相同内容的文件共用时下载链接立即失效。只能删除自己生成的文件，id 来自 list_documents。"),
+		mcp.WithInputSchema[DeleteDocumentsReq](),
+		mcp.WithOutputSchema[DeleteDocumentsResult](),
+	)
+	return tool
+}
+
+type DeleteDocumentsReq struct {
+	Ids []int64 `json:"ids" jsonschema_description:"要删除的文件 ID"`
+}
+
+type DeleteDocumentsResult struct {
+	Deleted  []int64 `json:"deleted"`
+	NotFound []int64 `json:"not_found,omitempty"`
+}
+
+func deleteDocuments(ctx context.Context, request mcp.CallToolRequest, dr DeleteDocumentsReq) (*DeleteDocumentsResult, error) {
+	if len(dr.Ids) == 0 {
+		return nil, errors.New("ids is required")
+	}
+
+	var docs []dao.GeneratedDocument
+	if err := client.Mysql.WithContext(ctx).Scopes(ownedDocuments(ctx)).Where("id IN ?", dr.Ids).Find(&docs).Error; err != nil {
+		return nil, err
+	}
+
+	result := &DeleteDocumentsResult{Deleted: []int64{}}
+	found := make(map[int64]bool, len(docs))
+	for _, doc := range docs {
+		found[doc.ID] = true
+		if err := client.Mysql.WithContext(ctx).Delete(&doc).Error; err != nil {
+			return nil, err
+		}
+		// 内容相同的文件共用一个对象，没有其他记录引用时才删除对象
+		var refs int64
+		if err := client.Mysql.WithContext(ctx).Model(&dao.GeneratedDocument{}).Where("object_name = ?", doc.ObjectName).Count(&refs).Error; err != nil {
+			return nil, err
+		}
+		if refs == 0 {
+			if err := storage.Store.Remove(ctx, doc.ObjectName); err != nil {
+				return nil, fmt.Errorf("delete %s failed: %w", doc.ObjectName, err)
+			}
+		}
+		result.Deleted = append(result.Deleted, doc.ID)
+	}
+	for _, id := range dr.Ids {
+		if !found[id] {
+			result.NotFound = append(result.NotFound, id)
+		}
+	}
+
+	return result, nil
+}
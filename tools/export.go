@@ -0,0 +1,280 @@
+package tools
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/mark3labs/mcp-go/mcp"
+	"io"
+	"iter"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// maxExportRows 单次导出的最大行数，查询结果会先完整加载到内存，再逐行编码上传
+const maxExportRows = 100000
+
+// exportSource 用工具的原始参数查询完整结果集，返回的切片会被序列化成文件
+type exportSource func(ctx context.Context, args json.RawMessage, maxRows int) (any, error)
+
+var exportSources = map[string]exportSource{
+	"search_users": func(ctx context.Context, args json.RawMessage, maxRows int) (any, error) {
+		var sq SearchUserReq
+		if err := json.Unmarshal(args, &sq); err != nil {
+			return nil, err
+		}
+		sq.Limit = maxRows
+		return querySearchUsers(ctx, sq, "export_query")
+	},
+	"get_user_benefit_records": func(ctx context.Context, args json.RawMessage, maxRows int) (any, error) {
+		var req QueryUserBenefitRecords
+		if err := json.Unmarshal(args, &req); err != nil {
+			return nil, err
+		}
+		return queryAllBenefitRecords(ctx, req, maxRows)
+	},
+}
+
+func getExportQueryTool() mcp.Tool {
+	tool := mcp.NewTool("export_query",
+		mcp.WithDescription(`
+在服务端执行列表类工具的查询，并把完整结果集直接写成文件（csv/xlsx/json），只返回下载链接和行数。
+需要导出大量数据（几百上千行）时使用本工具，不要先调用列表工具再把结果逐行复制到 generate_document_link。
+支持的工具：search_users、get_user_benefit_records。arguments 与对应工具的参数相同，limit 会被忽略。
+最多导出 100000 行，文件逐行编码、边生成边上传，结果很大时可以用 compression 压缩为 gzip 或 zip。
+`),
+		mcp.WithInputSchema[ExportQueryReq](),
+		mcp.WithOutputSchema[ExportResult](),
+	)
+	return tool
+}
+
+type ExportQueryReq struct {
+	Tool          string         `json:"tool" jsonschema:"enum=search_users,enum=get_user_benefit_records" jsonschema_description:"要导出结果的列表工具名"`
+	Arguments     map[string]any `json:"arguments,omitempty" jsonschema_description:"传给该工具的参数，与直接调用该工具时相同"`
+	Format        string         `json:"format" jsonschema:"enum=csv,enum=xlsx,enum=json" jsonschema_description:"文件格式"`
+	Filename      string         `json:"filename,omitempty" jsonschema_description:"文件名（不含扩展名），默认使用工具名"`
+	Compression   string         `json:"compression,omitempty" jsonschema:"enum=none,enum=gzip,enum=zip" jsonschema_description:"压缩方式，默认不压缩"`
+	ExpiryMinutes int            `json:"expiry_minutes,omitempty" jsonschema_description:"下载链接有效期（分钟），不填使用服务端默认值，最长 7 天"`
+}
+
+type ExportResult struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Format    string    `json:"format"`
+	Rows      int       `json:"rows"`
+	Truncated bool      `json:"truncated"`
+}
+
+func exportQuery(ctx context.Context, request mcp.CallToolRequest, er ExportQueryReq) (*ExportResult, error) {
+	source, ok := exportSources[er.Tool]
+	if !ok {
+		return nil, fmt.Errorf("tool %q does not support export", er.Tool)
+	}
+
+	args, err := json.Marshal(er.Arguments)
+	if err != nil {
+		return nil, err
+	}
+	data, err := source(ctx, args, maxExportRows)
+	if err != nil {
+		return nil, err
+	}
+
+	filename := er.Filename
+	if filename == "" {
+		filename = er.Tool
+	}
+	result, err := writeExport(ctx, filename, er.Format, er.Compression, data, linkExpiry(er.ExpiryMinutes))
+	if err != nil {
+		return nil, err
+	}
+	result.Truncated = result.Rows >= maxExportRows
+
+	return result, nil
+}
+
+// writeExport 把结果切片逐行编码为指定格式并流式上传，返回下载链接和行数。
+// 查询结果本身（最多 maxExportRows 行）仍在内存中，编码时不再复制出整张表的 JSON 和单元格
+func writeExport(ctx context.Context, filename, format, compression string, data any, expiry time.Duration) (*ExportResult, error) {
+	rows, err := newExportRows(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var write func(w io.Writer) error
+	var ext, mimeType string
+	switch format {
+	case "json":
+		ext, mimeType = ".json", "application/json"
+		write = rows.writeJSON
+	case "xlsx":
+		ext, mimeType = ".xlsx", xlsxMimeType
+		write = func(w io.Writer) error {
+			return writeXlsx(w, rows.header, rows.All())
+		}
+	case "csv", "":
+		format = "csv"
+		ext, mimeType = ".csv", "text/csv; charset=utf-8"
+		write = func(w io.Writer) error {
+			return writeCSV(w, rows.header, rows.All())
+		}
+	default:
+		return nil, fmt.Errorf("unsupported format: %s", format)
+	}
+
+	doc, err := writeDocument(ctx, filename, ext, mimeType, "attachment", compression, expiry, write)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ExportResult{URL: doc.URL, ExpiresAt: doc.ExpiresAt, Format: format, Rows: rows.Len()}, nil
+}
+
+// exportRows 逐行访问导出的结果切片：创建时遍历一遍，按键首次出现的顺序生成表头，写文件时每次只编码一行
+type exportRows struct {
+	items  reflect.Value
+	header []string
+	index  map[string]int
+}
+
+func newExportRows(data any) (*exportRows, error) {
+	rows := &exportRows{items: reflect.ValueOf(data), index: make(map[string]int)}
+	if !rows.items.IsValid() {
+		return rows, nil
+	}
+	if rows.items.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("export source returned %T, expected a slice", data)
+	}
+
+	for i := range rows.Len() {
+		raw, err := json.Marshal(rows.items.Index(i).Interface())
+		if err != nil {
+			return nil, err
+		}
+		keys, err := objectKeys(raw)
+		if err != nil {
+			return nil, err
+		}
+		for _, k := range keys {
+			if _, ok := rows.index[k]; !ok {
+				rows.index[k] = len(rows.header)
+				rows.header = append(rows.header, k)
+			}
+		}
+	}
+
+	return rows, nil
+}
+
+func (r *exportRows) Len() int {
+	if !r.items.IsValid() {
+		return 0
+	}
+
+	return r.items.Len()
+}
+
+// All 按表头顺序逐行返回单元格，可以重复遍历
+func (r *exportRows) All() iter.Seq2[[]any, error] {
+	return func(yield func([]any, error) bool) {
+		for i := range r.Len() {
+			raw, err := json.Marshal(r.items.Index(i).Interface())
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			var obj map[string]any
+			if err := decodeJSON(raw, &obj); err != nil {
+				yield(nil, err)
+				return
+			}
+			row := make([]any, len(r.header))
+			for k, v := range obj {
+				row[r.index[k]] = v
+			}
+			if !yield(row, nil) {
+				return
+			}
+		}
+	}
+}
+
+// writeJSON 逐个元素写出 JSON 数组
+func (r *exportRows) writeJSON(w io.Writer) error {
+	if r.Len() == 0 {
+		_, err := io.WriteString(w, "[]\n")
+		return err
+	}
+
+	if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+	for i := range r.Len() {
+		data, err := json.MarshalIndent(r.items.Index(i).Interface(), "  ", "  ")
+		if err != nil {
+			return err
+		}
+		sep := ",\n  "
+		if i == 0 {
+			sep = "\n  "
+		}
+		if _, err := io.WriteString(w, sep); err != nil {
+			return err
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "\n]\n")
+
+	return err
+}
+
+// writeCSV 写出带 BOM 的 CSV，方便 Excel 直接打开
+func writeCSV(out io.Writer, header []string, rows iter.Seq2[[]any, error]) error {
+	if _, err := io.WriteString(out, "\uFEFF"); err != nil {
+		return err
+	}
+	w := csv.NewWriter(out)
+	if err := w.Write(header); err != nil {
+		return err
+	}
+
+	record := make([]string, len(header))
+	for row, err := range rows {
+		if err != nil {
+			return err
+		}
+		for i := range record {
+			record[i] = ""
+			if i < len(row) {
+				record[i] = cellText(row[i])
+			}
+		}
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+
+	return w.Error()
+}
+
+func cellText(v any) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case json.Number:
+		return val.String()
+	case []any, map[string]any:
+		data, _ := json.Marshal(val)
+		return string(data)
+	default:
+		return strings.TrimSpace(fmt.Sprint(val))
+	}
+}
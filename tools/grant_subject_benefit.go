@@ -0,0 +1,296 @@
+package tools
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"gorm.io/gorm"
+	"log"
+	"mcp/server/auth"
+	"mcp/server/client"
+	"mcp/server/dao"
+	"slices"
+	"time"
+)
+
+const (
+	maxGrantUsers = 100000
+	// 等待同一栏目的其他发放任务结束的最长秒数
+	grantLockTimeoutSeconds = 10
+	// 结果中最多列出的用户ID数量，其余只给出总数
+	maxListedUsers = 200
+)
+
+func getGrantSubjectBenefitTool() mcp.Tool {
+	tool := mcp.NewTool("grant_subject_benefit",
+		mcp.WithDescription(`
+给一批用户发放栏目免费权益（写入 activity_free_subjects 表）：
+- 已经领取过该栏目的用户会被跳过，重复调用不会重复发放
+- 用户较多时通过 user_ids_object 传入已上传的 CSV 文件
+- 建议先用 dry_run=true 预览将要发放的人数，再正式执行
+- 正式执行需要调用方拥有配置的发放角色，并会通过客户端向用户弹出确认，用户确认后才会写入，所有写入都会记录到审计表
+栏目ID请先通过 resolve_subject 获取。
+`),
+		mcp.WithInputSchema[GrantSubjectBenefitReq](),
+		mcp.WithDestructiveHintAnnotation(false),
+		mcp.WithIdempotentHintAnnotation(true),
+	)
+	return tool
+}
+
+type GrantSubjectBenefitReq struct {
+	UserIds       []int  `json:"user_ids,omitempty" jsonschema_description:"要发放权益的用户ID列表，单次最多 100000 个"`
+	UserIdsObject string `json:"user_ids_object,omitempty" jsonschema_description:"用户ID较多时，先用 create_upload_link 让用户上传 CSV 文件，再传入返回的 handle，不要逐个列出"`
+	UserIdsColumn string `json:"user_ids_column,omitempty" jsonschema_description:"CSV 文件有表头时，用户ID所在的列名，默认取第一列"`
+	SubjectId     int    `json:"subject_id" jsonschema_description:"栏目ID"`
+	FreeDays      int    `json:"free_days" jsonschema_description:"免费天数，1 到 3650"`
+	Reason        string `json:"reason,omitempty" jsonschema_description:"发放原因，例如活动名称，会记录到审计表"`
+	DryRun        bool   `json:"dry_run,omitempty" jsonschema_description:"为 true 时只预览，不写入"`
+}
+
+type GrantSubjectBenefitResult struct {
+	BatchId        string `json:"batch_id,omitempty"`
+	DryRun         bool   `json:"dry_run"`
+	SubjectId      int    `json:"subject_id"`
+	SubjectName    string `json:"subject_name,omitempty"`
+	FreeDays       int    `json:"free_days"`
+	Requested      int    `json:"requested"`
+	AlreadyGranted int    `json:"already_granted"`
+	Granted        int    `json:"granted"`
+	ToGrant        int    `json:"to_grant"`
+	// 最多列出前 200 个用户ID
+	AlreadyGrantedIds []int `json:"already_granted_ids,omitempty"`
+	ToGrantIds        []int `json:"to_grant_ids,omitempty"`
+}
+
+type grantConfirmation struct {
+	Confirm bool `json:"confirm"`
+}
+
+func grantSubjectBenefit(ctx context.Context, request mcp.CallToolRequest, gr GrantSubjectBenefitReq) (*mcp.CallToolResult, error) {
+	if gr.SubjectId <= 0 {
+		return mcp.NewToolResultError("subject_id is required"), nil
+	}
+	if gr.FreeDays <= 0 || gr.FreeDays > 3650 {
+		return mcp.NewToolResultError("free_days must be between 1 and 3650"), nil
+	}
+	caller := auth.CallerFromContext(ctx)
+	if !gr.DryRun && !auth.CanGrant(ctx) {
+		return mcp.NewToolResultError(fmt.Sprintf("caller %s is not allowed to grant subject benefits, only dry_run is available", caller.Name)), nil
+	}
+
+	userIds := gr.UserIds
+	if gr.UserIdsObject != "" {
+		ids, err := loadIdsFromObject(ctx, gr.UserIdsObject, gr.UserIdsColumn)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("load user_ids_object failed: %v", err)), nil
+		}
+		userIds = append(userIds, ids...)
+	}
+	userIds = uniqueInts(userIds)
+	if len(userIds) == 0 {
+		return mcp.NewToolResultError("user_ids or user_ids_object is required"), nil
+	}
+	if len(userIds) > maxGrantUsers {
+		return mcp.NewToolResultError(fmt.Sprintf("too many user_ids: %d, at most %d per call", len(userIds), maxGrantUsers)), nil
+	}
+
+	result := GrantSubjectBenefitResult{
+		DryRun:      gr.DryRun,
+		SubjectId:   gr.SubjectId,
+		SubjectName: subjectName(gr.SubjectId),
+		FreeDays:    gr.FreeDays,
+		Requested:   len(userIds),
+	}
+
+	toGrant, granted, err := splitGrantedUsers(client.Mysql.WithContext(ctx), userIds, gr.SubjectId)
+	if err != nil {
+		return nil, err
+	}
+	result.AlreadyGranted = len(granted)
+	result.AlreadyGrantedIds = granted[:min(len(granted), maxListedUsers)]
+
+	if gr.DryRun || len(toGrant) == 0 {
+		result.ToGrant = len(toGrant)
+		result.ToGrantIds = toGrant[:min(len(toGrant), maxListedUsers)]
+		return mcp.NewToolResultStructuredOnly(result), nil
+	}
+
+	// 正式写入前必须经过用户确认
+	message := fmt.Sprintf("即将为 %d 个用户发放栏目《%s》(id=%d) %d 天免费权益（另有 %d 个用户已领取，将跳过）。确认发放？",
+		len(toGrant), result.SubjectName, gr.SubjectId, gr.FreeDays, len(granted))
+	confirmed, err := confirmWithUser(ctx, message)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("无法向用户确认，未写入任何数据: %v", err)), nil
+	}
+	if !confirmed {
+		return mcp.NewToolResultText("用户取消了发放，未写入任何数据。"), nil
+	}
+
+	result.BatchId = fmt.Sprintf("grant_%d", time.Now().UnixNano())
+	err = client.Mysql.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		// 同一栏目的发放任务串行执行，锁在事务提交后才释放，后一个任务在事务内重新检查时能看到前一个任务写入的记录；
+		// activity_free_subjects 是业务表，不能为此加唯一索引
+		unlock, err := grantLock(conn, gr.SubjectId)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+
+		return conn.Transaction(func(tx *gorm.DB) error {
+			toGrant, _, err = splitGrantedUsers(tx, toGrant, gr.SubjectId)
+			if err != nil {
+				return err
+			}
+			if len(toGrant) == 0 {
+				return nil
+			}
+
+			rows := make([]dao.ActivityFreeSubject, 0, len(toGrant))
+			for _, uid := range toGrant {
+				rows = append(rows, dao.ActivityFreeSubject{UserId: uid, SubjectId: gr.SubjectId, SubjectFreeDays: gr.FreeDays})
+			}
+			if err := tx.CreateInBatches(&rows, 500).Error; err != nil {
+				return err
+			}
+
+			audits := make([]dao.BenefitGrantAudit, 0, len(rows))
+			for _, r := range rows {
+				audits = append(audits, dao.BenefitGrantAudit{
+					BatchId:         result.BatchId,
+					Operator:        caller.Name,
+					OperatorRole:    caller.Role,
+					FreeSubjectId:   r.ID,
+					UserId:          r.UserId,
+					SubjectId:       r.SubjectId,
+					SubjectFreeDays: r.SubjectFreeDays,
+					Reason:          gr.Reason,
+				})
+			}
+			return tx.CreateInBatches(&audits, 500).Error
+		})
+	})
+	if errors.Is(err, errGrantBusy) {
+		return mcp.NewToolResultError("另一个发放任务正在为该栏目发放权益，本次未写入任何数据，请稍后用 dry_run 重新预览"), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result.Granted = len(toGrant)
+	log.Printf("[grant] batch=%s caller=%s subject_id=%d free_days=%d granted=%d", result.BatchId, caller.Name, gr.SubjectId, gr.FreeDays, result.Granted)
+
+	return mcp.NewToolResultStructuredOnly(result), nil
+}
+
+var errGrantBusy = errors.New("another grant for the subject is in progress")
+
+// grantLock 用 MySQL 命名锁按栏目互斥，锁属于当前连接，调用方必须在同一个连接上执行事务
+func grantLock(conn *gorm.DB, subjectId int) (func(), error) {
+	name := fmt.Sprintf("grant_subject_benefit:%d", subjectId)
+	var acquired sql.NullInt64
+	if err := conn.Raw("SELECT GET_LOCK(?, ?)", name, grantLockTimeoutSeconds).Scan(&acquired).Error; err != nil {
+		return nil, err
+	}
+	if !acquired.Valid || acquired.Int64 != 1 {
+		return nil, errGrantBusy
+	}
+
+	return func() {
+		// 请求被取消时也要释放，否则锁会随连接留在连接池中
+		if err := conn.WithContext(context.WithoutCancel(conn.Statement.Context)).Exec("SELECT RELEASE_LOCK(?)", name).Error; err != nil {
+			log.Printf("[grant] release lock %s failed: %v", name, err)
+		}
+	}, nil
+}
+
+// splitGrantedUsers 将用户分为尚未领取和已领取该栏目的两组
+func splitGrantedUsers(db *gorm.DB, userIds []int, subjectId int) (toGrant, granted []int, err error) {
+	seen := make(map[int]bool)
+	for ids := range slices.Chunk(userIds, 1000) {
+		var existing []int
+		if err := db.Model(&dao.ActivityFreeSubject{}).
+			Where("user_id in (?) and subject_id = ?", ids, subjectId).
+			Distinct().Pluck("user_id", &existing).Error; err != nil {
+			return nil, nil, err
+		}
+		for _, uid := range existing {
+			seen[uid] = true
+		}
+		granted = append(granted, existing...)
+	}
+
+	for _, uid := range userIds {
+		if !seen[uid] {
+			toGrant = append(toGrant, uid)
+		}
+	}
+
+	return toGrant, granted, nil
+}
+
+// confirmWithUser 通过 MCP elicitation 请求用户确认
+func confirmWithUser(ctx context.Context, message string) (bool, error) {
+	s := server.ServerFromContext(ctx)
+	if s == nil {
+		return false, server.ErrNoActiveSession
+	}
+
+	res, err := s.RequestElicitation(ctx, mcp.ElicitationRequest{
+		Params: mcp.ElicitationParams{
+			Message: message,
+			RequestedSchema: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"confirm": map[string]any{"type": "boolean", "description": "确认执行"},
+				},
+				"required": []string{"confirm"},
+			},
+		},
+	})
+	if err != nil {
+		return false, err
+	}
+	if res.Action != mcp.ElicitationResponseActionAccept {
+		return false, nil
+	}
+
+	// Content 反序列化后是 map[string]any，转一次 json 绑定到结构体
+	data, err := json.Marshal(res.Content)
+	if err != nil {
+		return false, err
+	}
+	var c grantConfirmation
+	if err := json.Unmarshal(data, &c); err != nil {
+		return false, err
+	}
+
+	return c.Confirm, nil
+}
+
+func subjectName(id int) string {
+	for _, s := range subjectCatalog() {
+		if s.Id == id {
+			return s.Name
+		}
+	}
+
+	return ""
+}
+
+func uniqueInts(ids []int) []int {
+	seen := make(map[int]bool, len(ids))
+	var out []int
+	for _, id := range ids {
+		if id > 0 && !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+
+	return out
+}
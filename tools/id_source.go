@@ -0,0 +1,97 @@
+package tools
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"mcp/server/storage"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	// maxObjectIds 单个 CSV 文件最多读取的 id 数量
+	maxObjectIds = 1000000
+	// idQueryBatchSize 每批 IN (...) 查询的 id 数量，远低于 MySQL 65535 个占位符的上限
+	idQueryBatchSize = 5000
+)
+
+// loadIdsFromObject 从对象存储中流式读取 CSV 文件并解析出 id 列表。
+// 首行没有数字时当作表头，column 指定要读取的列名；否则取第一列。
+func loadIdsFromObject(ctx context.Context, objectName, column string) ([]int, error) {
+	if err := checkObjectHandle(ctx, objectName); err != nil {
+		return nil, err
+	}
+
+	obj, err := storage.Store.Get(ctx, objectName)
+	if err != nil {
+		return nil, err
+	}
+	defer obj.Close()
+
+	reader := csv.NewReader(obj)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	var ids []int
+	colIdx := 0
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read %s failed: %w", objectName, err)
+		}
+
+		if line == 1 {
+			// Excel 导出的 UTF-8 CSV 会带 BOM
+			record[0] = strings.TrimPrefix(record[0], "\uFEFF")
+		}
+
+		if line == 1 && isHeaderRow(record) {
+			if column != "" {
+				if colIdx = slices.IndexFunc(record, func(v string) bool {
+					return strings.EqualFold(strings.TrimSpace(v), column)
+				}); colIdx < 0 {
+					return nil, fmt.Errorf("column %q not found in %s", column, objectName)
+				}
+			}
+			continue
+		}
+
+		if colIdx >= len(record) {
+			continue
+		}
+		value := strings.TrimSpace(record[colIdx])
+		if value == "" {
+			continue
+		}
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			// 不回显单元格内容，文件中可能有手机号等隐私数据
+			return nil, fmt.Errorf("%s line %d column %d: value is not a valid id", objectName, line, colIdx+1)
+		}
+
+		ids = append(ids, id)
+		if len(ids) > maxObjectIds {
+			return nil, fmt.Errorf("%s contains more than %d ids", objectName, maxObjectIds)
+		}
+	}
+
+	return uniqueInts(ids), nil
+}
+
+// isHeaderRow 首行中没有任何数字单元格时当作表头
+func isHeaderRow(record []string) bool {
+	for _, v := range record {
+		if _, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,88 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"github.com/mark3labs/mcp-go/mcp"
+	"gorm.io/gorm"
+	"mcp/server/client"
+	"mcp/server/dao"
+)
+
+func getLookupUserTool() mcp.Tool {
+	tool := mcp.NewTool("lookup_user",
+		mcp.WithDescription("精确查找单个用户：按用户ID、用户名、邮箱、手机号或微信 unionid 中的任意一个精确匹配，返回该用户的资料及绑定的登录方式。查找某一个具体用户时使用本工具，不要用 search_users。"),
+		mcp.WithInputSchema[LookupUserReq](),
+		mcp.WithOutputSchema[UserProfile](),
+	)
+	return tool
+}
+
+type LookupUserReq struct {
+	Id            int64  `json:"id,omitempty" jsonschema_description:"用户ID"`
+	Username      string `json:"username,omitempty" jsonschema_description:"用户名"`
+	Email         string `json:"email,omitempty" jsonschema_description:"邮箱"`
+	Mobile        string `json:"mobile,omitempty" jsonschema_description:"手机号"`
+	WeixinUnionId string `json:"weixin_union_id,omitempty" jsonschema_description:"微信 unionid"`
+}
+
+// LoginProviders 标记用户绑定了哪些登录方式，只返回是否绑定，不返回具体的 id
+type LoginProviders struct {
+	Password bool `json:"password"`
+	Weibo    bool `json:"weibo"`
+	Weixin   bool `json:"weixin"`
+	QQ       bool `json:"qq"`
+	OAuth0   bool `json:"oauth0"`
+	OAuth1   bool `json:"oauth1"`
+	Apple    bool `json:"apple"`
+}
+
+type UserProfile struct {
+	User
+	Portrait       string
+	LoginProviders LoginProviders
+}
+
+func lookupUser(ctx context.Context, request mcp.CallToolRequest, lr LookupUserReq) (*UserProfile, error) {
+	db := client.Mysql.WithContext(ctx).Model(&dao.UserModel{})
+
+	switch {
+	case lr.Id > 0:
+		db = db.Where("id = ?", lr.Id)
+	case lr.Username != "":
+		db = db.Where("username = ?", lr.Username)
+	case lr.Email != "":
+		db = db.Where("email = ?", lr.Email)
+	case lr.Mobile != "":
+		db = db.Where("mobile = ?", lr.Mobile)
+	case lr.WeixinUnionId != "":
+		db = db.Where("weixin_union_id = ?", lr.WeixinUnionId)
+	default:
+		return nil, errors.New("one of id, username, email, mobile or weixin_union_id is required")
+	}
+
+	var u dao.UserModel
+	if err := db.Take(&u).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		return nil, err
+	}
+
+	profile := &UserProfile{
+		Portrait: u.Portrait.String,
+		LoginProviders: LoginProviders{
+			Password: len(u.PasswordHash) > 0,
+			Weibo:    u.WeiboId.Valid && u.WeiboId.String != "",
+			Weixin:   u.WeixinUnionId.Valid && u.WeixinUnionId.String != "",
+			QQ:       (u.QqOpenId.Valid && u.QqOpenId.String != "") || (u.QqUnionId.Valid && u.QqUnionId.String != ""),
+			OAuth0:   u.OAuth0Id.Valid && u.OAuth0Id.String != "",
+			OAuth1:   u.OAuth1Id.Valid && u.OAuth1Id.String != "",
+			Apple:    u.AppleUserIdentifier.Valid && u.AppleUserIdentifier.String != "",
+		},
+	}
+	profile.Eich(&u)
+	applyUserMasking(ctx, "lookup_user", []*User{&profile.User})
+
+	return profile, nil
+}
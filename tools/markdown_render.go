@@ -0,0 +1,523 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/go-pdf/fpdf"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	extast "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/text"
+	"html/template"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"mcp/server/config"
+	"mcp/server/storage"
+	"os"
+	"strconv"
+	"strings"
+)
+
+var markdown = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+var htmlReportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body { font-family: "PingFang SC", "Hiragino Sans GB", "Microsoft YaHei", "Noto Sans CJK SC", "Noto Sans SC", "Source Han Sans SC", sans-serif; max-width: 960px; margin: 40px auto; padding: 0 24px; color: #1f2328; line-height: 1.7; }
+h1, h2, h3 { border-bottom: 1px solid #d0d7de; padding-bottom: .3em; }
+table { border-collapse: collapse; width: 100%; margin: 16px 0; }
+th, td { border: 1px solid #d0d7de; padding: 6px 12px; }
+th { background: #f6f8fa; }
+tr:nth-child(even) td { background: #fbfcfd; }
+code { background: #f6f8fa; padding: .2em .4em; border-radius: 4px; font-family: Menlo, Consolas, monospace; }
+pre { background: #f6f8fa; padding: 16px; overflow: auto; border-radius: 6px; }
+pre code { padding: 0; }
+blockquote { color: #57606a; border-left: 4px solid #d0d7de; margin: 0; padding: 0 16px; }
+img { max-width: 100%; }
+</style>
+</head>
+<body>
+{{.Body}}
+</body>
+</html>
+`))
+
+// renderMarkdownHTML 渲染为独立的 HTML 页面。不开启 unsafe，模型输出中的原始 HTML 会被忽略；
+// 本服务生成的图片以 data URI 内嵌，下载链接过期后图表仍然可以显示
+func renderMarkdownHTML(ctx context.Context, title, md string) ([]byte, error) {
+	source := []byte(md)
+	doc := markdown.Parser().Parse(text.NewReader(source))
+	err := ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if img, ok := n.(*ast.Image); ok && entering {
+			if data, format, ok := loadGeneratedImage(ctx, string(img.Destination)); ok {
+				img.Destination = []byte("data:image/" + format + ";base64," + base64.StdEncoding.EncodeToString(data))
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	if err := markdown.Renderer().Render(&body, source, doc); err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	err = htmlReportTemplate.Execute(&out, map[string]any{
+		"Title": title,
+		"Body":  template.HTML(body.String()),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return out.Bytes(), nil
+}
+
+const (
+	pdfFont       = "cjk"
+	pdfLineHeight = 6.0
+	pdfBodySize   = 10.5
+)
+
+// renderMarkdownPDF 用纯 Go 把 markdown 渲染为 PDF，字体从配置读取并嵌入到文件中
+func renderMarkdownPDF(ctx context.Context, title, md string) ([]byte, error) {
+	cfg := config.Cfg.Report
+	if cfg == nil || cfg.FontPath == "" {
+		return nil, errors.New("PDF 输出需要在配置 report.fontPath 中指定支持中文的 TTF 字体")
+	}
+	regular, err := os.ReadFile(cfg.FontPath)
+	if err != nil {
+		return nil, fmt.Errorf("read font failed: %w", err)
+	}
+	bold := regular
+	if cfg.BoldFontPath != "" {
+		if bold, err = os.ReadFile(cfg.BoldFontPath); err != nil {
+			return nil, fmt.Errorf("read bold font failed: %w", err)
+		}
+	}
+
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.AddUTF8FontFromBytes(pdfFont, "", regular)
+	pdf.AddUTF8FontFromBytes(pdfFont, "B", bold)
+	// 中文字体一般没有斜体，斜体沿用常规字形
+	pdf.AddUTF8FontFromBytes(pdfFont, "I", regular)
+	pdf.AddUTF8FontFromBytes(pdfFont, "BI", bold)
+	pdf.SetTitle(title, true)
+	pdf.SetMargins(18, 18, 18)
+	pdf.SetAutoPageBreak(true, 18)
+	pdf.AliasNbPages("")
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-12)
+		pdf.SetFont(pdfFont, "", 8)
+		pdf.SetTextColor(128, 128, 128)
+		pdf.CellFormat(0, 6, fmt.Sprintf("%d / {nb}", pdf.PageNo()), "", 0, "C", false, 0, "")
+	})
+	pdf.AddPage()
+	if err := pdf.Error(); err != nil {
+		return nil, err
+	}
+
+	source := []byte(md)
+	doc := markdown.Parser().Parse(text.NewReader(source))
+	r := &pdfRenderer{ctx: ctx, pdf: pdf, source: source}
+	r.renderBlocks(doc)
+
+	var out bytes.Buffer
+	if err := pdf.Output(&out); err != nil {
+		return nil, err
+	}
+
+	return out.Bytes(), nil
+}
+
+type pdfRenderer struct {
+	ctx    context.Context
+	pdf    *fpdf.Fpdf
+	source []byte
+	images int
+}
+
+func (r *pdfRenderer) resetStyle() {
+	r.pdf.SetFont(pdfFont, "", pdfBodySize)
+	r.pdf.SetTextColor(31, 35, 40)
+}
+
+func (r *pdfRenderer) renderBlocks(parent ast.Node) {
+	for n := parent.FirstChild(); n != nil; n = n.NextSibling() {
+		r.renderBlock(n)
+	}
+}
+
+func (r *pdfRenderer) renderBlock(n ast.Node) {
+	pdf := r.pdf
+	left, _, _, _ := pdf.GetMargins()
+	r.resetStyle()
+
+	switch node := n.(type) {
+	case *ast.Heading:
+		size := map[int]float64{1: 18, 2: 15, 3: 13}[node.Level]
+		if size == 0 {
+			size = 11.5
+		}
+		pdf.Ln(2)
+		pdf.Bookmark(string(r.plainText(node)), node.Level-1, -1)
+		pdf.SetFont(pdfFont, "B", size)
+		r.renderInlines(node, size*0.5, "B")
+		pdf.Ln(size * 0.5)
+		if node.Level <= 2 {
+			w, _ := pdf.GetPageSize()
+			pdf.SetDrawColor(208, 215, 222)
+			pdf.Line(left, pdf.GetY(), w-left, pdf.GetY())
+		}
+		pdf.Ln(2)
+	case *ast.Paragraph, *ast.TextBlock:
+		r.renderInlines(node, pdfLineHeight, "")
+		if _, ok := node.(*ast.Paragraph); ok {
+			pdf.Ln(pdfLineHeight + 2)
+		} else {
+			pdf.Ln(pdfLineHeight)
+		}
+	case *ast.List:
+		r.renderList(node)
+		pdf.Ln(1)
+	case *ast.Blockquote:
+		pdf.SetLeftMargin(left + 6)
+		pdf.SetX(left + 6)
+		r.renderBlocks(node)
+		pdf.SetLeftMargin(left)
+	case *ast.FencedCodeBlock, *ast.CodeBlock:
+		var code strings.Builder
+		lines := node.Lines()
+		for i := 0; i < lines.Len(); i++ {
+			seg := lines.At(i)
+			code.Write(seg.Value(r.source))
+		}
+		pdf.SetFont(pdfFont, "", 9)
+		pdf.SetFillColor(246, 248, 250)
+		pdf.MultiCell(0, 5, strings.TrimRight(code.String(), "\n"), "", "L", true)
+		pdf.Ln(3)
+	case *ast.ThematicBreak:
+		w, _ := pdf.GetPageSize()
+		pdf.Ln(2)
+		pdf.SetDrawColor(208, 215, 222)
+		pdf.Line(left, pdf.GetY(), w-left, pdf.GetY())
+		pdf.Ln(4)
+	case *extast.Table:
+		r.renderTable(node)
+		pdf.Ln(3)
+	case *ast.HTMLBlock:
+		// 原始 HTML 不渲染
+	default:
+		r.renderBlocks(node)
+	}
+}
+
+func (r *pdfRenderer) renderList(list *ast.List) {
+	pdf := r.pdf
+	left, _, _, _ := pdf.GetMargins()
+	number := list.Start
+	for item := list.FirstChild(); item != nil; item = item.NextSibling() {
+		marker := "•"
+		if list.IsOrdered() {
+			marker = strconv.Itoa(number) + "."
+			number++
+		}
+
+		r.resetStyle()
+		pdf.SetX(left)
+		pdf.CellFormat(6, pdfLineHeight, marker, "", 0, "R", false, 0, "")
+		pdf.SetLeftMargin(left + 7)
+		pdf.SetX(left + 7)
+		for child := item.FirstChild(); child != nil; child = child.NextSibling() {
+			if nested, ok := child.(*ast.List); ok {
+				pdf.Ln(pdfLineHeight)
+				r.renderList(nested)
+				continue
+			}
+			r.renderInlines(child, pdfLineHeight, "")
+		}
+		pdf.SetLeftMargin(left)
+		pdf.Ln(pdfLineHeight)
+	}
+}
+
+// renderInlines 按顺序输出行内元素，粗体/斜体通过切换字体样式实现
+func (r *pdfRenderer) renderInlines(parent ast.Node, lineHeight float64, style string) {
+	pdf := r.pdf
+	for n := parent.FirstChild(); n != nil; n = n.NextSibling() {
+		switch node := n.(type) {
+		case *ast.Text:
+			pdf.Write(lineHeight, string(node.Value(r.source)))
+			if node.HardLineBreak() {
+				pdf.Ln(lineHeight)
+			} else if node.SoftLineBreak() {
+				pdf.Write(lineHeight, " ")
+			}
+		case *ast.String:
+			pdf.Write(lineHeight, string(node.Value))
+		case *ast.Emphasis:
+			next := style
+			if node.Level >= 2 {
+				next += "B"
+			} else {
+				next += "I"
+			}
+			_, size := pdf.GetFontSize()
+			pdf.SetFont(pdfFont, normalizeFontStyle(next), size)
+			r.renderInlines(node, lineHeight, next)
+			pdf.SetFont(pdfFont, normalizeFontStyle(style), size)
+		case *ast.CodeSpan:
+			pdf.SetTextColor(207, 34, 46)
+			pdf.Write(lineHeight, string(r.plainText(node)))
+			pdf.SetTextColor(31, 35, 40)
+		case *ast.Link:
+			pdf.SetTextColor(9, 105, 218)
+			pdf.WriteLinkString(lineHeight, string(r.plainText(node)), string(node.Destination))
+			pdf.SetTextColor(31, 35, 40)
+		case *ast.AutoLink:
+			url := string(node.URL(r.source))
+			pdf.SetTextColor(9, 105, 218)
+			pdf.WriteLinkString(lineHeight, url, url)
+			pdf.SetTextColor(31, 35, 40)
+		case *ast.Image:
+			r.renderImage(node, lineHeight)
+		case *extast.Strikethrough:
+			r.renderInlines(node, lineHeight, style)
+		case *ast.RawHTML:
+			// 原始 HTML 不渲染
+		default:
+			r.renderInlines(node, lineHeight, style)
+		}
+	}
+}
+
+// renderImage 本服务生成的 png/jpeg 图片（例如 generate_chart 的图表）嵌入 PDF，其他图片只保留替代文字
+func (r *pdfRenderer) renderImage(img *ast.Image, lineHeight float64) {
+	if r.embedImage(string(img.Destination), lineHeight) {
+		return
+	}
+
+	alt := string(r.plainText(img))
+	if alt == "" {
+		alt = string(img.Destination)
+	}
+	r.pdf.Write(lineHeight, "["+alt+"]")
+}
+
+// maxEmbedImageSize 嵌入 PDF、HTML 的单张图片大小上限
+const maxEmbedImageSize = 10 << 20
+
+// loadGeneratedImage 读取本服务生成的 png/jpeg 图片，返回内容和格式
+func loadGeneratedImage(ctx context.Context, dest string) ([]byte, string, bool) {
+	objectName, ok := generatedObjectName(dest)
+	if !ok {
+		return nil, "", false
+	}
+
+	obj, err := storage.Store.Get(ctx, objectName)
+	if err != nil {
+		return nil, "", false
+	}
+	defer obj.Close()
+	data, err := io.ReadAll(io.LimitReader(obj, maxEmbedImageSize+1))
+	if err != nil || len(data) > maxEmbedImageSize {
+		return nil, "", false
+	}
+	// 先确认能解码，避免无效图片让整个文件出错
+	_, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || (format != "png" && format != "jpeg") {
+		return nil, "", false
+	}
+
+	return data, format, true
+}
+
+func (r *pdfRenderer) embedImage(dest string, lineHeight float64) bool {
+	data, format, ok := loadGeneratedImage(r.ctx, dest)
+	if !ok {
+		return false
+	}
+
+	pdf := r.pdf
+	r.images++
+	name := fmt.Sprintf("img%d", r.images)
+	opts := fpdf.ImageOptions{ImageType: format, ReadDpi: true}
+	pdf.RegisterImageOptionsReader(name, opts, bytes.NewReader(data))
+	if pdf.Error() != nil {
+		return false
+	}
+
+	left, _, right, _ := pdf.GetMargins()
+	pageWidth, _ := pdf.GetPageSize()
+	info := pdf.GetImageInfo(name)
+	width := min(info.Width(), pageWidth-left-right)
+	pdf.Ln(lineHeight)
+	pdf.ImageOptions(name, left, pdf.GetY(), width, 0, true, opts, 0, "")
+
+	return true
+}
+
+// generatedObjectName 从本服务签发的预签名链接中取出 generated/ 下的对象名
+func generatedObjectName(dest string) (string, bool) {
+	if strings.Contains(dest, "..") {
+		return "", false
+	}
+	if strings.HasPrefix(dest, generatedPrefix) {
+		return dest, true
+	}
+
+	objectName, ok := storage.Store.KeyFromURL(dest)
+	if !ok || !strings.HasPrefix(objectName, generatedPrefix) {
+		return "", false
+	}
+
+	return objectName, true
+}
+
+func normalizeFontStyle(style string) string {
+	var out string
+	if strings.Contains(style, "B") {
+		out += "B"
+	}
+	if strings.Contains(style, "I") {
+		out += "I"
+	}
+
+	return out
+}
+
+func (r *pdfRenderer) renderTable(table *extast.Table) {
+	pdf := r.pdf
+	var rows [][]string
+	var header int
+	for row := table.FirstChild(); row != nil; row = row.NextSibling() {
+		var cells []string
+		for cell := row.FirstChild(); cell != nil; cell = cell.NextSibling() {
+			cells = append(cells, string(r.plainText(cell)))
+		}
+		if _, ok := row.(*extast.TableHeader); ok {
+			header++
+		}
+		rows = append(rows, cells)
+	}
+	if len(rows) == 0 {
+		return
+	}
+
+	cols := len(table.Alignments)
+	for _, row := range rows {
+		cols = max(cols, len(row))
+	}
+
+	// 按每列最长内容分配宽度，每列至少占平均宽度的一半
+	pageW, _ := pdf.GetPageSize()
+	left, _, right, _ := pdf.GetMargins()
+	available := pageW - left - right
+	pdf.SetFont(pdfFont, "", 9)
+	natural := make([]float64, cols)
+	total := 0.0
+	for _, row := range rows {
+		for i, c := range row {
+			natural[i] = max(natural[i], pdf.GetStringWidth(c)+4)
+		}
+	}
+	for i := range natural {
+		natural[i] = max(natural[i], available/float64(cols)/2)
+		total += natural[i]
+	}
+	widths := make([]float64, cols)
+	for i := range widths {
+		widths[i] = natural[i] * min(1, available/total)
+	}
+
+	const lh = 5.0
+	for ri, row := range rows {
+		isHeader := ri < header
+		if isHeader {
+			pdf.SetFont(pdfFont, "B", 9)
+			pdf.SetFillColor(246, 248, 250)
+		} else {
+			pdf.SetFont(pdfFont, "", 9)
+		}
+
+		lines := 1
+		for i := 0; i < cols; i++ {
+			if i < len(row) {
+				lines = max(lines, len(pdf.SplitText(row[i], widths[i])))
+			}
+		}
+		height := float64(lines) * lh
+		_, pageH := pdf.GetPageSize()
+		_, _, _, bottom := pdf.GetMargins()
+		if pdf.GetY()+height > pageH-bottom {
+			pdf.AddPage()
+		}
+
+		x, y := left, pdf.GetY()
+		for i := 0; i < cols; i++ {
+			var cell string
+			if i < len(row) {
+				cell = row[i]
+			}
+			align := "L"
+			if i < len(table.Alignments) {
+				switch table.Alignments[i] {
+				case extast.AlignCenter:
+					align = "C"
+				case extast.AlignRight:
+					align = "R"
+				}
+			}
+
+			pdf.SetDrawColor(208, 215, 222)
+			pdf.Rect(x, y, widths[i], height, map[bool]string{true: "FD", false: "D"}[isHeader])
+			pdf.SetXY(x, y)
+			pdf.MultiCell(widths[i], lh, cell, "", align, false)
+			x += widths[i]
+		}
+		pdf.SetXY(left, y+height)
+	}
+	r.resetStyle()
+}
+
+// plainText 拼接节点下所有文本
+func (r *pdfRenderer) plainText(n ast.Node) []byte {
+	var buf bytes.Buffer
+	_ = ast.Walk(n, func(c ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch t := c.(type) {
+		case *ast.Text:
+			buf.Write(t.Value(r.source))
+			if t.SoftLineBreak() {
+				buf.WriteByte(' ')
+			}
+		case *ast.String:
+			buf.Write(t.Value)
+		case *ast.CodeSpan:
+			for cc := t.FirstChild(); cc != nil; cc = cc.NextSibling() {
+				if tt, ok := cc.(*ast.Text); ok {
+					buf.Write(tt.Value(r.source))
+				}
+			}
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+
+	return buf.Bytes()
+}
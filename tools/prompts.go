@@ -0,0 +1,237 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"mcp/server/client"
+	"mcp/server/dao"
+	"mcp/server/util"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxDigestNews 新闻日报最多带入的资讯条数
+const maxDigestNews = 50
+
+// analystPrompts 常用分析流程的提示词，告诉客户端模型按什么顺序调用哪些工具
+func analystPrompts() []server.ServerPrompt {
+	return []server.ServerPrompt{
+		{Prompt: getNewsDigestPrompt(), Handler: newsDigestPrompt},
+		{Prompt: getBenefitAuditPrompt(), Handler: benefitAuditPrompt},
+		{Prompt: getUserGrowthPrompt(), Handler: userGrowthPrompt},
+	}
+}
+
+func getNewsDigestPrompt() mcp.Prompt {
+	return mcp.NewPrompt("daily_news_digest",
+		mcp.WithPromptDescription("汇总某一天的资讯（默认只看今日焦点），写成新闻日报并生成文件"),
+		mcp.WithArgument("date", mcp.ArgumentDescription("日期，格式 2006-01-02，默认今天")),
+		mcp.WithArgument("focus_only", mcp.ArgumentDescription("是否只看今日焦点，true 或 false，默认 true")),
+		mcp.WithArgument("format", mcp.ArgumentDescription("日报文件格式：markdown、html 或 pdf，默认 pdf")),
+		mcp.WithArgument("source", mcp.ArgumentDescription("只看指定来源的资讯，默认不限")),
+		mcp.WithArgument("author", mcp.ArgumentDescription("只看指定作者（display_author）的资讯，默认不限")),
+		mcp.WithArgument("order_by", mcp.ArgumentDescription("排序字段，取值见 schema://content_messages 的 sort_fields，默认 created_at，均为倒序")),
+	)
+}
+
+func newsDigestPrompt(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	args := request.Params.Arguments
+	day := dayStart(time.Now())
+	if args["date"] != "" {
+		t, err := time.ParseInLocation(time.DateOnly, strings.TrimSpace(args["date"]), util.Loc)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date %q, expected 2006-01-02", args["date"])
+		}
+		day = t
+	}
+	focusOnly := args["focus_only"] != "false"
+	format := promptChoice(args["format"], "pdf", digestFormats...)
+	order := "created_at desc"
+	if args["order_by"] != "" {
+		var err error
+		if order, err = orderClause(&dao.ContentMessage{}, args["order_by"], "desc"); err != nil {
+			return nil, err
+		}
+	}
+
+	tx := client.Mysql.WithContext(ctx).Model(&dao.ContentMessage{}).
+		Select("id, title, sub_title, summary, source, display_author, url, is_premium, is_todays_focus, created_at").
+		Where("created_at >= ? AND created_at < ?", day.UTC(), day.AddDate(0, 0, 1).UTC()).
+		Where("is_deleted = ? AND is_withdrawn = ?", false, false)
+	if focusOnly {
+		tx = tx.Where("is_todays_focus = ?", true)
+	}
+	if source := strings.TrimSpace(args["source"]); source != "" {
+		tx = tx.Where("source = ?", source)
+	}
+	if author := strings.TrimSpace(args["author"]); author != "" {
+		tx = tx.Where("display_author = ?", author)
+	}
+	var messages []dao.ContentMessage
+	if err := tx.Order(order).Limit(maxDigestNews).Find(&messages).Error; err != nil {
+		return nil, err
+	}
+
+	news := make([]NewsResource, 0, len(messages))
+	for _, msg := range messages {
+		news = append(news, toNewsResource(msg))
+	}
+	data, err := json.Marshal(news)
+	if err != nil {
+		return nil, err
+	}
+
+	date := day.Format(time.DateOnly)
+	scope := "全部资讯"
+	if focusOnly {
+		scope = "今日焦点资讯"
+	}
+	text := fmt.Sprintf(`请为 %s 的%s写一份新闻日报。附件是当天的资讯列表（共 %d 条，按 %s 排序，最多 %d 条），只含标题和摘要。
+步骤：
+1. 按主题归类资讯，每个主题写 2-3 句概述，标注来源（source）和时间；重要资讯需要细节时读取 news://{id} 资源获取正文，不要编造内容。
+2. 开头写一段不超过 200 字的当日要点。
+3. 调用 generate_document_link，file_type 为 %s，filename 为 news_digest_%s，把日报正文（markdown）作为 content 生成文件。
+4. 回复日报要点和下载链接。列表为空时直接说明当天没有%s，不要生成文件。`,
+		date, scope, len(news), order, maxDigestNews, format, strings.ReplaceAll(date, "-", ""), scope)
+
+	return mcp.NewGetPromptResult(fmt.Sprintf("%s 新闻日报", date), []mcp.PromptMessage{
+		mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent(text)),
+		mcp.NewPromptMessage(mcp.RoleUser, mcp.NewEmbeddedResource(mcp.TextResourceContents{
+			URI:      fmt.Sprintf("news-digest://%s?focus_only=%t", date, focusOnly),
+			MIMEType: "application/json",
+			Text:     string(data),
+		})),
+	}), nil
+}
+
+func getBenefitAuditPrompt() mcp.Prompt {
+	return mcp.NewPrompt("benefit_claim_audit",
+		mcp.WithPromptDescription("核对一批用户是否领取了指定栏目（如《脱水研报》）的免费权益，以及权益是否仍有效"),
+		mcp.WithArgument("subject", mcp.RequiredArgument(), mcp.ArgumentDescription("栏目名称，例如 脱水研报")),
+		mcp.WithArgument("user_ids", mcp.ArgumentDescription("用户ID，用逗号分隔；用户较多时改用 user_ids_object")),
+		mcp.WithArgument("user_ids_object", mcp.ArgumentDescription("create_upload_link 返回的 handle，文件中是要核对的用户ID")),
+	)
+}
+
+func benefitAuditPrompt(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	args := request.Params.Arguments
+	subject := strings.TrimSpace(args["subject"])
+	if subject == "" {
+		return nil, fmt.Errorf("argument %q is required", "subject")
+	}
+	userIds, err := promptIds(args["user_ids"])
+	if err != nil {
+		return nil, err
+	}
+
+	var users string
+	switch {
+	case args["user_ids_object"] != "":
+		users = fmt.Sprintf(`user_ids_object 为 %q`, args["user_ids_object"])
+	case len(userIds) > 0:
+		data, _ := json.Marshal(userIds)
+		users = fmt.Sprintf("user_ids 为 %s", data)
+	default:
+		users = "不传 user_ids（查询该栏目的全部领取记录）"
+	}
+
+	text := fmt.Sprintf(`请核对用户领取「%s」栏目免费权益的情况。
+步骤：
+1. 调用 resolve_subject，names 为 [%q]，确定栏目ID；有多个候选时先问我选哪一个，不要猜测。
+2. 调用 get_user_benefit_records，subject_ids 为上一步的栏目ID，%s。
+3. 按「仍有效 / 已过期 / 未领取」三类汇总人数；传了用户ID时列出未领取的用户ID，以及 7 天内到期的用户ID和到期时间。
+4. 明细超过 50 条时不要逐条列出，调用 export_query（tool 为 get_user_benefit_records，arguments 与第 2 步相同，format 为 xlsx）导出文件并给出下载链接。`,
+		subject, subject, users)
+
+	return mcp.NewGetPromptResult(fmt.Sprintf("「%s」权益领取核对", subject), []mcp.PromptMessage{
+		mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent(text)),
+	}), nil
+}
+
+func getUserGrowthPrompt() mcp.Prompt {
+	return mcp.NewPrompt("user_growth_report",
+		mcp.WithPromptDescription("统计一段时间的新增用户、活跃用户和留存，绘制图表并生成用户增长报告"),
+		mcp.WithArgument("start_date", mcp.ArgumentDescription("开始日期，格式 2006-01-02，默认 30 天前")),
+		mcp.WithArgument("end_date", mcp.ArgumentDescription("结束日期，格式 2006-01-02，默认今天")),
+		mcp.WithArgument("granularity", mcp.ArgumentDescription("时间粒度：day、week 或 month，默认 day")),
+		mcp.WithArgument("group_by", mcp.ArgumentDescription("拆分维度：platform_name 或 xgb_channel，默认不拆分")),
+	)
+}
+
+func userGrowthPrompt(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	args := request.Params.Arguments
+	end := dayStart(time.Now())
+	start := end.AddDate(0, 0, -30)
+	for name, target := range map[string]*time.Time{"start_date": &start, "end_date": &end} {
+		if args[name] == "" {
+			continue
+		}
+		t, err := time.ParseInLocation(time.DateOnly, strings.TrimSpace(args[name]), util.Loc)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q, expected 2006-01-02", name, args[name])
+		}
+		*target = t
+	}
+	if start.After(end) {
+		return nil, fmt.Errorf("start_date must not be after end_date")
+	}
+	granularity := promptChoice(args["granularity"], "day", statsGranularities...)
+
+	stats := map[string]any{
+		"granularity": granularity,
+		"start_time":  start.Format(time.RFC3339),
+		"end_time":    end.AddDate(0, 0, 1).Add(-time.Second).Format(time.RFC3339),
+	}
+	if groupBy := promptChoice(args["group_by"], "", statsGroupBy...); groupBy != "" {
+		stats["group_by"] = groupBy
+	}
+	data, _ := json.Marshal(stats)
+
+	period := fmt.Sprintf("%s 至 %s", start.Format(time.DateOnly), end.Format(time.DateOnly))
+	text := fmt.Sprintf(`请生成 %s 的用户增长报告。user_stats 的公共参数：%s
+步骤：
+1. 调用 user_stats，metric 为 new_users。
+2. 调用 user_stats，metric 为 active_users（只记录最后一次活跃，是下限估计，报告中要注明）。
+3. 调用 user_stats，metric 为 retention，不传 group_by；retained、rates 中的 null 表示该期尚未到达，不要当作 0。
+4. 调用 generate_chart 分别绘制新增用户和活跃用户的折线图（type 为 line，format 为 png，labels 为各期 period，拆分维度时每个 group 一个 series）。
+5. 用 markdown 写报告：总览（总新增、日均新增、环比变化）、趋势分析、留存分析；把第 4 步返回的 markdown 图片引用插入对应章节。
+6. 调用 generate_document_link，file_type 为 pdf，filename 为 user_growth_report，生成报告文件并回复主要结论和下载链接。`,
+		period, data)
+
+	return mcp.NewGetPromptResult(fmt.Sprintf("%s 用户增长报告", period), []mcp.PromptMessage{
+		mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent(text)),
+	}), nil
+}
+
+// promptChoice 提示词参数只能取 choices 中的值，为空或不合法时返回 def
+func promptChoice(value, def string, choices ...string) string {
+	value = strings.ToLower(strings.TrimSpace(value))
+	for _, c := range choices {
+		if value == c {
+			return c
+		}
+	}
+
+	return def
+}
+
+// promptIds 解析逗号、空格或换行分隔的用户ID
+func promptIds(value string) ([]int64, error) {
+	fields := strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == '，' || r == ' ' || r == '\n' || r == '\t'
+	})
+	ids := make([]int64, 0, len(fields))
+	for _, f := range fields {
+		id, err := strconv.ParseInt(f, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid user id %q", f)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,390 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"io"
+	"mcp/server/client"
+	"mcp/server/config"
+	"mcp/server/dao"
+	"mcp/server/storage"
+	"mcp/server/util"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+func reportTemplates() []config.ReportTemplateConfig {
+	if config.Cfg.Report == nil {
+		return nil
+	}
+
+	return config.Cfg.Report.Templates
+}
+
+func findReportTemplate(name string) (config.ReportTemplateConfig, bool) {
+	for _, t := range reportTemplates() {
+		if t.Name == name {
+			return t, true
+		}
+	}
+
+	return config.ReportTemplateConfig{}, false
+}
+
+func getRenderReportTool() mcp.Tool {
+	var names []string
+	var desc strings.Builder
+	desc.WriteString(`
+按预先配置的报告模板生成固定结构的报告（日报、活动复盘等），模板会自动查询内容统计、用户统计和栏目领取数据，
+生成文件后返回下载链接。用户要求生成某个固定报告时优先使用本工具，不要自己逐个调用统计工具再拼接。
+可用模板：
+`)
+	for _, t := range reportTemplates() {
+		names = append(names, t.Name)
+		fmt.Fprintf(&desc, "- %s（%s）：%s", t.Name, reportFormat(t), t.Description)
+		for _, p := range t.Params {
+			fmt.Fprintf(&desc, "\n  - 参数 %s：%s", p.Name, p.Description)
+			if p.Required {
+				desc.WriteString("（必填）")
+			}
+		}
+		desc.WriteString("\n")
+	}
+
+	tool := mcp.NewTool("render_report",
+		mcp.WithDescription(desc.String()),
+		mcp.WithString("template", mcp.Required(), mcp.Enum(names...), mcp.Description("模板名称")),
+		mcp.WithObject("params", mcp.Description("模板参数，键为参数名，值均为字符串"), mcp.AdditionalProperties(map[string]any{"type": "string"})),
+		mcp.WithString("filename", mcp.Description("文件名（不含扩展名），默认使用模板名称")),
+		mcp.WithNumber("expiry_minutes", mcp.Description("下载链接有效期（分钟），不填使用服务端默认值，最长 7 天")),
+		mcp.WithOutputSchema[ReportResult](),
+	)
+	return tool
+}
+
+type RenderReportReq struct {
+	Template      string            `json:"template"`
+	Params        map[string]string `json:"params"`
+	Filename      string            `json:"filename"`
+	ExpiryMinutes int               `json:"expiry_minutes"`
+}
+
+type ReportResult struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Template  string    `json:"template"`
+	Format    string    `json:"format"`
+	Fixes     []string  `json:"fixes,omitempty"`
+}
+
+func reportFormat(t config.ReportTemplateConfig) string {
+	if t.Format == "" {
+		return "markdown"
+	}
+
+	return t.Format
+}
+
+func renderReport(ctx context.Context, request mcp.CallToolRequest, rr RenderReportReq) (*ReportResult, error) {
+	tpl, ok := findReportTemplate(rr.Template)
+	if !ok {
+		return nil, fmt.Errorf("report template %q not found", rr.Template)
+	}
+
+	content, err := executeReportTemplate(ctx, tpl, rr.Params)
+	if err != nil {
+		return nil, err
+	}
+
+	filename := rr.Filename
+	if filename == "" {
+		filename = tpl.Name
+	}
+	format := reportFormat(tpl)
+	doc, fixes, err := generateDocument(ctx, GenerateFileReq{FileName: filename, FileType: format, Content: content, ExpiryMinutes: rr.ExpiryMinutes})
+	if err != nil {
+		return nil, err
+	}
+
+	return &ReportResult{URL: doc.URL, ExpiresAt: doc.ExpiresAt, Template: tpl.Name, Format: format, Fixes: fixes}, nil
+}
+
+// reportData 模板中可以通过 .Params.xxx 读取参数，.Now 为当前北京时间
+type reportData struct {
+	Params map[string]string
+	Now    time.Time
+}
+
+func executeReportTemplate(ctx context.Context, tpl config.ReportTemplateConfig, args map[string]string) (string, error) {
+	params := make(map[string]string, len(tpl.Params))
+	for _, p := range tpl.Params {
+		v := strings.TrimSpace(args[p.Name])
+		if v == "" {
+			v = p.Default
+		}
+		if v == "" && p.Required {
+			return "", fmt.Errorf("param %q is required for report template %s", p.Name, tpl.Name)
+		}
+		params[p.Name] = v
+	}
+
+	text, err := loadReportTemplate(ctx, tpl)
+	if err != nil {
+		return "", err
+	}
+	t, err := template.New(tpl.Name).Option("missingkey=zero").Funcs(reportFuncs(ctx)).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("parse report template %s failed: %w", tpl.Name, err)
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, reportData{Params: params, Now: time.Now().In(util.Loc)}); err != nil {
+		return "", fmt.Errorf("render report template %s failed: %w", tpl.Name, err)
+	}
+
+	return buf.String(), nil
+}
+
+// loadReportTemplate 配置中的模板直接返回，对象存储中的模板每次重新读取，修改文件后无需重启
+func loadReportTemplate(ctx context.Context, tpl config.ReportTemplateConfig) (string, error) {
+	if tpl.Object == "" {
+		return tpl.Template, nil
+	}
+
+	obj, err := storage.Store.Get(ctx, tpl.Object)
+	if err != nil {
+		return "", err
+	}
+	defer obj.Close()
+	data, err := io.ReadAll(obj)
+	if err != nil {
+		return "", fmt.Errorf("read report template %s failed: %w", tpl.Object, err)
+	}
+
+	return string(data), nil
+}
+
+// reportFuncs 模板中可用的函数，数据函数复用各个工具的查询逻辑
+func reportFuncs(ctx context.Context) template.FuncMap {
+	return template.FuncMap{
+		"userStats": func(metric, granularity string, start, end any, groupBy ...string) (*UserStatsResult, error) {
+			startTime, err := reportTime(start)
+			if err != nil {
+				return nil, err
+			}
+			endTime, err := reportTime(end)
+			if err != nil {
+				return nil, err
+			}
+			req := UserStatsReq{Metric: metric, Granularity: granularity, StartTime: &startTime, EndTime: &endTime}
+			if len(groupBy) > 0 {
+				req.GroupBy = groupBy[0]
+			}
+			return userStats(ctx, mcp.CallToolRequest{}, req)
+		},
+		"contentStats": func(granularity string, start, end any) ([]UserStatsPoint, error) {
+			startTime, err := reportTime(start)
+			if err != nil {
+				return nil, err
+			}
+			endTime, err := reportTime(end)
+			if err != nil {
+				return nil, err
+			}
+			return countContentByPeriod(ctx, granularity, startTime, endTime)
+		},
+		"contentMessages": func(keyword string, start, end any, limit int) ([]dao.ContentMessage, error) {
+			startTime, err := reportTime(start)
+			if err != nil {
+				return nil, err
+			}
+			endTime, err := reportTime(end)
+			if err != nil {
+				return nil, err
+			}
+			return queryContentMessages(ctx, getContentMessagesReq{
+				Keyword:        keyword,
+				StartTime:      startTime.In(util.Loc).Format(time.DateTime),
+				EndTime:        endTime.In(util.Loc).Format(time.DateTime),
+				Limit:          min(max(limit, 1), 100),
+				OrderBy:        "created_at",
+				OrderDirection: "desc",
+			})
+		},
+		"benefitSummary": func(subjectIds ...any) ([]BenefitSummary, error) {
+			ids, err := reportIds(subjectIds)
+			if err != nil {
+				return nil, err
+			}
+			return benefitSummaries(ctx, QueryUserBenefitRecords{SubjectIds: ids}, benefitBatches(nil), time.Now())
+		},
+		"subjectName": subjectName,
+		// subjectIds 栏目目录中全部栏目的 ID，逗号分隔，可直接传给 benefitSummary
+		"subjectIds": func() string {
+			ids := make([]string, 0)
+			for _, s := range subjectCatalog() {
+				ids = append(ids, strconv.Itoa(s.Id))
+			}
+			return strings.Join(ids, ",")
+		},
+		"now": func() time.Time {
+			return time.Now().In(util.Loc)
+		},
+		"daysAgo": func(n int) time.Time {
+			return dayStart(time.Now().In(util.Loc)).AddDate(0, 0, -n)
+		},
+		"dayStart": func(v any) (time.Time, error) {
+			t, err := reportTime(v)
+			return dayStart(t), err
+		},
+		"dayEnd": func(v any) (time.Time, error) {
+			t, err := reportTime(v)
+			return dayStart(t).AddDate(0, 0, 1).Add(-time.Second), err
+		},
+		"date": func(layout string, v any) (string, error) {
+			t, err := reportTime(v)
+			return t.In(util.Loc).Format(layout), err
+		},
+		"default": func(def, v string) string {
+			if v == "" {
+				return def
+			}
+			return v
+		},
+		"percent": func(part, total int64) string {
+			if total == 0 {
+				return "-"
+			}
+			return fmt.Sprintf("%.1f%%", float64(part)*100/float64(total))
+		},
+		"json": func(v any) (string, error) {
+			data, err := json.Marshal(v)
+			return string(data), err
+		},
+		// csv 把参数写成一行 CSV（不含换行），用于 csv/xlsx 模板
+		"csv": func(fields ...any) (string, error) {
+			record := make([]string, len(fields))
+			for i, f := range fields {
+				record[i] = cellText(f)
+			}
+			var buf bytes.Buffer
+			w := csv.NewWriter(&buf)
+			if err := w.Write(record); err != nil {
+				return "", err
+			}
+			w.Flush()
+			return strings.TrimRight(buf.String(), "\n"), w.Error()
+		},
+		// cell 转义 markdown 表格单元格中的竖线和换行
+		"cell": func(v any) string {
+			return strings.NewReplacer("|", `\|`, "\r\n", " ", "\n", " ").Replace(cellText(v))
+		},
+	}
+}
+
+func dayStart(t time.Time) time.Time {
+	t = t.In(util.Loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, util.Loc)
+}
+
+// reportTime 模板中的时间参数可以是 time.Time，也可以是 2006-01-02、2006-01-02 15:04:05 或 RFC3339 字符串
+func reportTime(v any) (time.Time, error) {
+	switch val := v.(type) {
+	case time.Time:
+		return val, nil
+	case *time.Time:
+		return *val, nil
+	case string:
+		for _, layout := range []string{time.DateOnly, time.DateTime, time.RFC3339} {
+			if t, err := time.ParseInLocation(layout, strings.TrimSpace(val), util.Loc); err == nil {
+				return t, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("invalid time %q, expected 2006-01-02 or 2006-01-02 15:04:05", val)
+	default:
+		return time.Time{}, fmt.Errorf("invalid time %v", v)
+	}
+}
+
+// reportIds 把整数或逗号分隔的字符串参数展开成 ID 列表
+func reportIds(values []any) ([]int, error) {
+	var ids []int
+	for _, v := range values {
+		switch val := v.(type) {
+		case int:
+			ids = append(ids, val)
+		case string:
+			for _, s := range strings.Split(val, ",") {
+				if s = strings.TrimSpace(s); s == "" {
+					continue
+				}
+				id, err := strconv.Atoi(s)
+				if err != nil {
+					return nil, fmt.Errorf("invalid id %q", s)
+				}
+				ids = append(ids, id)
+			}
+		default:
+			return nil, fmt.Errorf("invalid id %v", v)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("at least one id is required")
+	}
+
+	return ids, nil
+}
+
+// countContentByPeriod 按时间粒度统计发布的内容数量
+func countContentByPeriod(ctx context.Context, granularity string, startTime, endTime time.Time) ([]UserStatsPoint, error) {
+	var points []UserStatsPoint
+	err := client.Mysql.WithContext(ctx).Model(&dao.ContentMessage{}).
+		Select(periodExpr("created_at", granularity)+" AS period, COUNT(*) AS count").
+		Where("created_at between ? and ?", startTime.UTC(), endTime.UTC()).
+		Group("period").
+		Order("period").
+		Scan(&points).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return points, nil
+}
+
+// reportPrompt 每个报告模板同时注册为一个 prompt，客户端选择后引导模型调用 render_report
+func reportPrompt(tpl config.ReportTemplateConfig) (mcp.Prompt, server.PromptHandlerFunc) {
+	opts := []mcp.PromptOption{mcp.WithPromptDescription(tpl.Description)}
+	for _, p := range tpl.Params {
+		argOpts := []mcp.ArgumentOption{mcp.ArgumentDescription(p.Description)}
+		if p.Required {
+			argOpts = append(argOpts, mcp.RequiredArgument())
+		}
+		opts = append(opts, mcp.WithArgument(p.Name, argOpts...))
+	}
+
+	handler := func(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+		params := make(map[string]string)
+		for _, p := range tpl.Params {
+			if v := request.Params.Arguments[p.Name]; v != "" {
+				params[p.Name] = v
+			} else if p.Required {
+				return nil, fmt.Errorf("argument %q is required", p.Name)
+			}
+		}
+		args, _ := json.Marshal(map[string]any{"template": tpl.Name, "params": params})
+		text := fmt.Sprintf("请调用 render_report 工具生成报告「%s」，参数如下：\n%s\n生成后把下载链接发给我。", tpl.Description, args)
+
+		return mcp.NewGetPromptResult(tpl.Description, []mcp.PromptMessage{
+			mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent(text)),
+		}), nil
+	}
+
+	return mcp.NewPrompt("report_"+tpl.Name, opts...), handler
+}
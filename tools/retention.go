@@ -0,0 +1,274 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"github.com/mark3labs/mcp-go/mcp"
+	"log"
+	"mcp/server/auth"
+	"mcp/server/client"
+	"mcp/server/config"
+	"mcp/server/dao"
+	"mcp/server/storage"
+	"slices"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	generatedPrefix = "generated/"
+	retentionRuleId = "generated-retention"
+)
+
+// lifecycleApplied 为 true 时按时间过期由存储的生命周期规则负责，后台任务只按总大小清理
+var lifecycleApplied atomic.Bool
+
+func retentionConfig() config.RetentionConfig {
+	cfg := config.RetentionConfig{SweepIntervalMinutes: 60}
+	if c := config.Cfg.Retention; c != nil {
+		cfg.MaxAgeDays = c.MaxAgeDays
+		cfg.MaxTotalSizeMB = c.MaxTotalSizeMB
+		if c.SweepIntervalMinutes > 0 {
+			cfg.SweepIntervalMinutes = c.SweepIntervalMinutes
+		}
+	}
+
+	return cfg
+}
+
+// StartRetention 按配置清理 generated/ 下的文件：优先给存储设置生命周期规则按时间过期，
+// 存储不支持时改由后台任务删除；按总大小清理始终由后台任务完成。
+func StartRetention(ctx context.Context) {
+	cfg := retentionConfig()
+	if cfg.MaxAgeDays <= 0 && cfg.MaxTotalSizeMB <= 0 {
+		return
+	}
+
+	if cfg.MaxAgeDays > 0 {
+		if err := applyLifecycle(ctx, cfg.MaxAgeDays); err != nil {
+			log.Printf("[retention] set bucket lifecycle failed, fall back to sweeper: %v", err)
+		} else {
+			lifecycleApplied.Store(true)
+		}
+	}
+
+	go func() {
+		ticker := time.NewTicker(time.Duration(cfg.SweepIntervalMinutes) * time.Minute)
+		defer ticker.Stop()
+		for {
+			if err := sweepGenerated(ctx, cfg); err != nil {
+				log.Printf("[retention] sweep failed: %v", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// applyLifecycle 给 generated/ 设置按天过期的规则，local、memory 存储不支持
+func applyLifecycle(ctx context.Context, days int) error {
+	setter, ok := storage.Store.(storage.ExpirationSetter)
+	if !ok {
+		return errors.New("storage backend does not support expiration rules")
+	}
+
+	return setter.SetExpiration(ctx, retentionRuleId, generatedPrefix, days)
+}
+
+type RetentionCandidate struct {
+	ObjectName   string    `json:"object_name"`
+	Size         int64     `json:"size"`
+	LastModified time.Time `json:"last_modified"`
+	// 最近一次使用对象的时间：上传时间和登记记录中最新的 created_at（内容去重复用也会登记）取较晚者
+	LastUsed time.Time `json:"last_used"`
+	// age 表示超过保留天数，size 表示为满足总大小上限而删除
+	Reason string `json:"reason"`
+}
+
+type RetentionPlan struct {
+	MaxAgeDays        int   `json:"max_age_days"`
+	MaxTotalSizeBytes int64 `json:"max_total_size_bytes"`
+	// 为 true 时超过保留天数的文件由存储的生命周期规则删除，不在计划中列出
+	LifecycleApplied bool                 `json:"lifecycle_applied"`
+	TotalObjects     int                  `json:"total_objects"`
+	TotalSize        int64                `json:"total_size"`
+	DeleteCount      int                  `json:"delete_count"`
+	DeleteSize       int64                `json:"delete_size"`
+	Delete           []RetentionCandidate `json:"delete"`
+}
+
+// planRetention 列出 generated/ 下应删除的文件：先按时间，再从最久未使用的文件开始删除直到总大小不超过上限。
+// 内容去重会复用旧对象并签发新链接，因此按最近一次使用的时间而不是上传时间排序
+func planRetention(ctx context.Context, cfg config.RetentionConfig) (*RetentionPlan, error) {
+	plan := &RetentionPlan{
+		MaxAgeDays:        cfg.MaxAgeDays,
+		MaxTotalSizeBytes: int64(cfg.MaxTotalSizeMB) << 20,
+		LifecycleApplied:  lifecycleApplied.Load(),
+		Delete:            []RetentionCandidate{},
+	}
+
+	lastUsed, err := documentLastUsed(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var objects []RetentionCandidate
+	for obj, err := range storage.Store.List(ctx, generatedPrefix) {
+		if err != nil {
+			return nil, err
+		}
+		used := obj.LastModified
+		if t, ok := lastUsed[obj.Key]; ok && t.After(used) {
+			used = t
+		}
+		objects = append(objects, RetentionCandidate{ObjectName: obj.Key, Size: obj.Size, LastModified: obj.LastModified, LastUsed: used})
+		plan.TotalSize += obj.Size
+	}
+	plan.TotalObjects = len(objects)
+	slices.SortFunc(objects, func(a, b RetentionCandidate) int {
+		return a.LastUsed.Compare(b.LastUsed)
+	})
+
+	remaining := plan.TotalSize
+	cutoff := time.Now().AddDate(0, 0, -cfg.MaxAgeDays)
+	for _, obj := range objects {
+		switch {
+		case cfg.MaxAgeDays > 0 && !plan.LifecycleApplied && obj.LastUsed.Before(cutoff):
+			obj.Reason = "age"
+		case plan.MaxTotalSizeBytes > 0 && remaining > plan.MaxTotalSizeBytes:
+			obj.Reason = "size"
+		default:
+			continue
+		}
+		remaining -= obj.Size
+		plan.DeleteCount++
+		plan.DeleteSize += obj.Size
+		plan.Delete = append(plan.Delete, obj)
+	}
+
+	return plan, nil
+}
+
+// documentLastUsed 每个对象最新一条登记记录的时间
+func documentLastUsed(ctx context.Context) (map[string]time.Time, error) {
+	var rows []struct {
+		ObjectName string
+		LastUsed   time.Time
+	}
+	err := client.Mysql.WithContext(ctx).Model(&dao.GeneratedDocument{}).
+		Select("object_name, MAX(created_at) AS last_used").
+		Group("object_name").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	lastUsed := make(map[string]time.Time, len(rows))
+	for _, r := range rows {
+		lastUsed[r.ObjectName] = r.LastUsed
+	}
+
+	return lastUsed, nil
+}
+
+// reusedSince 计划生成之后对象是否又被登记（内容去重复用并签发了新链接），是则不能删除
+func reusedSince(ctx context.Context, objectName string, since time.Time) (bool, error) {
+	var count int64
+	err := client.Mysql.WithContext(ctx).Model(&dao.GeneratedDocument{}).
+		Where("object_name = ? AND created_at > ?", objectName, since).
+		Count(&count).Error
+
+	return count > 0, err
+}
+
+// sweepGenerated 删除计划中的文件和对应的登记记录，并清理已过期文件的登记记录
+func sweepGenerated(ctx context.Context, cfg config.RetentionConfig) error {
+	plan, err := planRetention(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	if len(plan.Delete) > 0 {
+		var removed []string
+		for _, c := range plan.Delete {
+			reused, err := reusedSince(ctx, c.ObjectName, c.LastUsed)
+			if err != nil {
+				return err
+			}
+			if reused {
+				continue
+			}
+			if err := storage.Store.Remove(ctx, c.ObjectName); err != nil {
+				log.Printf("[retention] remove %s failed: %v", c.ObjectName, err)
+				continue
+			}
+			removed = append(removed, c.ObjectName)
+		}
+		for chunk := range slices.Chunk(removed, 1000) {
+			if err := client.Mysql.WithContext(ctx).Where("object_name IN ?", chunk).Delete(&dao.GeneratedDocument{}).Error; err != nil {
+				return err
+			}
+		}
+		log.Printf("[retention] removed %d objects, %d bytes", len(removed), plan.DeleteSize)
+	}
+
+	// 生命周期规则删除的文件不会经过这里，按时间清理对应的登记记录；
+	// 只清理最新一条登记也已超过保留天数的对象，仍在使用的对象保留全部记录
+	if cfg.MaxAgeDays > 0 {
+		cutoff := time.Now().AddDate(0, 0, -cfg.MaxAgeDays)
+		expired := client.Mysql.Model(&dao.GeneratedDocument{}).
+			Select("object_name").
+			Group("object_name").
+			Having("MAX(created_at) < ?", cutoff)
+		// MySQL 不允许在删除的同一张表的子查询中直接引用该表，需要再包一层派生表
+		err := client.Mysql.WithContext(ctx).
+			Where("object_name IN (?)", client.Mysql.Table("(?) AS expired", expired).Select("object_name")).
+			Delete(&dao.GeneratedDocument{}).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func getRetentionReportTool() mcp.Tool {
+	tool := mcp.NewTool("retention_report",
+		mcp.WithDescription(`
+预览 generated/ 目录的清理计划（不会真正删除）：按配置的保留天数和总大小上限，列出后台清理任务将要删除的文件。
+用于确认清理策略是否合理，或者回答"为什么之前的文件找不到了"。仅限管理角色调用。
+`),
+		mcp.WithNumber("limit", mcp.Description("最多列出的文件数，默认 100，最大 1000；delete_count 和 delete_size 始终是完整统计")),
+		mcp.WithOutputSchema[RetentionPlan](),
+	)
+	return tool
+}
+
+type RetentionReportReq struct {
+	Limit int `json:"limit"`
+}
+
+func retentionReport(ctx context.Context, request mcp.CallToolRequest, rr RetentionReportReq) (*RetentionPlan, error) {
+	// 清理计划列出所有调用方生成的文件，只对管理角色开放
+	if !auth.CanViewRetention(ctx) {
+		return nil, errors.New("retention_report requires a role listed in retention.reportRoles")
+	}
+
+	plan, err := planRetention(ctx, retentionConfig())
+	if err != nil {
+		return nil, err
+	}
+
+	limit := rr.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+	if len(plan.Delete) > min(limit, 1000) {
+		plan.Delete = plan.Delete[:min(limit, 1000)]
+	}
+
+	return plan, nil
+}
@@ -125,7 +125,9 @@ func searchArticle(ctx context.Context, request mcp.CallToolRequest, params stri
 				}
 			}
 
-			return mcp.NewToolResultText(fullContent), nil
+			result := mcp.NewToolResultText(fullContent)
+			result.Content = append(result.Content, articleLinks(topArticleID)...)
+			return result, nil
 		}
 	}
 
@@ -145,5 +147,9 @@ func searchArticle(ctx context.Context, request mcp.CallToolRequest, params stri
 		finalContextBuilder.WriteString("\n---\n")
 	}
 
-	return mcp.NewToolResultText(finalContextBuilder.String()), nil
+	// 附带 article://{id} 资源链接，需要全文时客户端可以直接读取
+	result := mcp.NewToolResultText(finalContextBuilder.String())
+	result.Content = append(result.Content, articleLinks(sortedArticles...)...)
+
+	return result, nil
 }
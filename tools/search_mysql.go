@@ -18,10 +18,10 @@ MySQL 精确查询工具：
 - 不适用：意图模糊、纯自然语言语义理解类问题（如 “有哪些讲AI趋势的文章？”）
 如果用户请求涉及 “最新文章”、“按时间排序”、“topN 列表”、“字段条件”，必须优先使用此工具。
 `),
-		mcp.WithString("keyword", mcp.Description("结构化关键词（文章标题/内容中的字段），非语义问题")),
+		mcp.WithString("keyword", mcp.Description("在正文中模糊匹配的关键词（不匹配标题），非语义问题")),
 		mcp.WithString("start_time", mcp.Description("开始时间，格式为2006-01-02 15:04:05，最早可到2024-01-01 00:00:00")),
 		mcp.WithString("end_time", mcp.Description("结束时间，格式为2006-01-02 15:04:05，最晚可到当前时间")),
-		mcp.WithString("order_by", mcp.Description("排序字段，如 created_at")),
+		mcp.WithString("order_by", mcp.Description("排序字段，如 created_at，可选字段见 schema://content_messages 资源"), mcp.Enum(sortFields(&dao.ContentMessage{})...)),
 		mcp.WithString("order_direction", mcp.Description("排序方向，asc 或 desc")),
 		mcp.WithNumber("limit", mcp.Description("返回结果数量，默认为 5，最大不超过100")))
 	return tool
@@ -37,7 +37,20 @@ type getContentMessagesReq struct {
 }
 
 func getContentMessages(ctx context.Context, request mcp.CallToolRequest, searchReq getContentMessagesReq) (*mcp.CallToolResult, error) {
-	tx := client.Mysql.Model(&dao.ContentMessage{})
+	result, err := queryContentMessages(ctx, searchReq)
+	if err != nil {
+		return nil, err
+	}
+
+	// 每条资讯附带 news://{id} 资源链接，客户端可以直接读取详情
+	toolResult := mcp.NewToolResultStructuredOnly(result)
+	toolResult.Content = append(toolResult.Content, newsLinks(result)...)
+
+	return toolResult, nil
+}
+
+func queryContentMessages(ctx context.Context, searchReq getContentMessagesReq) ([]dao.ContentMessage, error) {
+	tx := client.Mysql.WithContext(ctx).Model(&dao.ContentMessage{})
 
 	if searchReq.StartTime != "" && searchReq.EndTime != "" {
 		startTime, _ := time.ParseInLocation(time.DateTime, searchReq.StartTime, util.Loc)
@@ -50,7 +63,11 @@ func getContentMessages(ctx context.Context, request mcp.CallToolRequest, search
 	}
 
 	if searchReq.OrderBy != "" {
-		tx = tx.Order(searchReq.OrderBy + " " + searchReq.OrderDirection)
+		order, err := orderClause(&dao.ContentMessage{}, searchReq.OrderBy, searchReq.OrderDirection)
+		if err != nil {
+			return nil, err
+		}
+		tx = tx.Order(order)
 	}
 
 	var result []dao.ContentMessage
@@ -58,5 +75,5 @@ func getContentMessages(ctx context.Context, request mcp.CallToolRequest, search
 		return nil, err
 	}
 
-	return mcp.NewToolResultStructuredOnly(result), nil
+	return result, nil
 }
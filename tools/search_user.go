@@ -2,27 +2,50 @@ package tools
 
 import (
 	"context"
+	"github.com/invopop/jsonschema"
 	"github.com/mark3labs/mcp-go/mcp"
+	"log"
+	"mcp/server/auth"
 	"mcp/server/client"
 	"mcp/server/dao"
+	"mcp/server/util"
 	"time"
 )
 
 func getSearchUserTool() mcp.Tool {
 	tool := mcp.NewTool("search_users",
-		mcp.WithDescription("根据自然语言查询用户数据库,支持根据时间范围查询。"),
+		mcp.WithDescription("根据自然语言查询用户数据库,支持按注册时间、最近活跃时间范围，以及平台、渠道、客户端版本、手机厂商、推送通道、封禁状态过滤。默认不返回已注销(deleted)的用户。最多返回 200 条，需要把全部结果导出为文件时使用 export_query。"),
 		mcp.WithInputSchema[SearchUserReq](),
 		mcp.WithOutputSchema[[]*User](),
 	)
 	return tool
 }
 
+// JSONSchemaExtend order_by 的可选值来自 dao.UserModel 的 query:"sort" 标签，与 schema://user_users 保持一致
+func (SearchUserReq) JSONSchemaExtend(s *jsonschema.Schema) {
+	if p, ok := s.Properties.Get("order_by"); ok {
+		for _, f := range sortFields(&dao.UserModel{}) {
+			p.Enum = append(p.Enum, f)
+		}
+	}
+}
+
 type SearchUserReq struct {
 	StartTime *time.Time `json:"start_time" jsonschema_description:"查询开始时间, RFC3339 timestamp, e.g. 2024-12-31T23:59:59+08:00"`
 	EndTime   *time.Time `json:"end_time" jsonschema_description:"查询结束时间, RFC3339 timestamp, e.g. 2024-12-31T23:59:59+08:00"`
-	OrderBy   string     `json:"order_by" jsonschema_description:"排序字段，目前支持根据创建时间(created_at)，最新活跃时间(last_active_at)排序"`
+	OrderBy   string     `json:"order_by" jsonschema_description:"排序字段，目前支持根据创建时间(created_at)，最新活跃时间(last_active_at)排序，可选字段见 schema://user_users 资源"`
 	Sort      string     `json:"sort" jsonschema_description:"排序规则，desc表示降序，asc表示升序"`
 	Limit     int        `json:"limit" jsonschema_description:"查询数量"`
+
+	LastActiveStart *time.Time `json:"last_active_start,omitempty" jsonschema_description:"最近活跃时间下限, RFC3339 timestamp, e.g. 2024-12-25T00:00:00+08:00"`
+	LastActiveEnd   *time.Time `json:"last_active_end,omitempty" jsonschema_description:"最近活跃时间上限, RFC3339 timestamp, e.g. 2024-12-31T23:59:59+08:00"`
+	PlatformNames   []string   `json:"platform_names,omitempty" jsonschema_description:"平台名称(platform_name)列表，满足任意一个即可，例如 [\"ios\", \"android\"]"`
+	XgbChannels     []string   `json:"xgb_channels,omitempty" jsonschema_description:"渠道(xgb_channel)列表，满足任意一个即可"`
+	ClientVersion   string     `json:"client_version,omitempty" jsonschema_description:"客户端版本号，精确匹配"`
+	Manufacturer    string     `json:"manufacturer,omitempty" jsonschema_description:"手机厂商，精确匹配，例如 Huawei"`
+	PushProvider    string     `json:"push_provider,omitempty" jsonschema_description:"推送通道，精确匹配"`
+	Banned          *bool      `json:"banned,omitempty" jsonschema_description:"true 只返回当前处于封禁中的用户，false 只返回未封禁用户，不填不过滤"`
+	IncludeDeleted  bool       `json:"include_deleted,omitempty" jsonschema_description:"是否包含已注销用户，默认 false"`
 }
 
 type User struct {
@@ -35,6 +58,13 @@ type User struct {
 	CreatedAt    time.Time
 	LastActiveAt time.Time
 	DeletedAt    *time.Time
+
+	PlatformName  string
+	XgbChannel    string
+	ClientVersion string
+	Manufacturer  string
+	PushProvider  string
+	BannedUntil   *time.Time
 }
 
 func (u *User) Eich(u1 *dao.UserModel) {
@@ -47,11 +77,52 @@ func (u *User) Eich(u1 *dao.UserModel) {
 	u.CreatedAt = u1.CreatedAt
 	u.LastActiveAt = u1.LastActiveAt
 	u.DeletedAt = u1.DeletedAt
+	u.PlatformName = u1.PlatformName
+	u.XgbChannel = u1.XgbChannel
+	u.ClientVersion = u1.ClientVersion.String
+	u.Manufacturer = u1.Manufacturer.String
+	u.PushProvider = u1.PushProvider.String
+	u.BannedUntil = u1.BannedUntil
+}
+
+// Mask 对手机号、邮箱、真实姓名、用户名、昵称脱敏，结果会被发送给第三方大模型
+func (u *User) Mask() {
+	u.Username = util.MaskHandle(u.Username)
+	u.Nickname = util.MaskHandle(u.Nickname)
+	u.Email = util.MaskEmail(u.Email)
+	u.Mobile = util.MaskMobile(u.Mobile)
+	u.RealName = util.MaskName(u.RealName)
+}
+
+// applyUserMasking 默认脱敏；只有配置中的特权角色能看到原始值，并记录每一次未脱敏访问
+func applyUserMasking(ctx context.Context, tool string, users []*User) {
+	if !auth.CanUnmask(ctx) {
+		for _, u := range users {
+			u.Mask()
+		}
+		return
+	}
+
+	ids := make([]int64, 0, len(users))
+	for _, u := range users {
+		ids = append(ids, u.Id)
+	}
+	caller := auth.CallerFromContext(ctx)
+	log.Printf("[pii] unmasked access: tool=%s caller=%s role=%s user_ids=%v", tool, caller.Name, caller.Role, ids)
 }
 
 func searchUser(ctx context.Context, request mcp.CallToolRequest, sq SearchUserReq) ([]*User, error) {
+	if sq.Limit <= 0 || sq.Limit > 200 {
+		sq.Limit = 200
+	}
+
+	return querySearchUsers(ctx, sq, "search_users")
+}
+
+// querySearchUsers 按条件查询用户，数量上限由调用方决定，导出文件时会放宽
+func querySearchUsers(ctx context.Context, sq SearchUserReq, tool string) ([]*User, error) {
 	var result []*dao.UserModel
-	db := client.Mysql.Model(&dao.UserModel{})
+	db := client.Mysql.WithContext(ctx).Model(&dao.UserModel{})
 
 	if sq.StartTime != nil {
 		db = db.Where("created_at >= ?", sq.StartTime)
@@ -61,12 +132,52 @@ func searchUser(ctx context.Context, request mcp.CallToolRequest, sq SearchUserR
 		db = db.Where("created_at <= ?", sq.EndTime)
 	}
 
-	if sq.OrderBy != "" {
-		db = db.Order(sq.OrderBy + " " + sq.Sort)
+	if sq.LastActiveStart != nil {
+		db = db.Where("last_active_at >= ?", sq.LastActiveStart)
 	}
 
-	if sq.Limit <= 0 || sq.Limit > 200 {
-		sq.Limit = 200
+	if sq.LastActiveEnd != nil {
+		db = db.Where("last_active_at <= ?", sq.LastActiveEnd)
+	}
+
+	if len(sq.PlatformNames) > 0 {
+		db = db.Where("platform_name in (?)", sq.PlatformNames)
+	}
+
+	if len(sq.XgbChannels) > 0 {
+		db = db.Where("xgb_channel in (?)", sq.XgbChannels)
+	}
+
+	if sq.ClientVersion != "" {
+		db = db.Where("client_version = ?", sq.ClientVersion)
+	}
+
+	if sq.Manufacturer != "" {
+		db = db.Where("manufacturer = ?", sq.Manufacturer)
+	}
+
+	if sq.PushProvider != "" {
+		db = db.Where("push_provider = ?", sq.PushProvider)
+	}
+
+	if sq.Banned != nil {
+		if *sq.Banned {
+			db = db.Where("banned_until > ?", time.Now())
+		} else {
+			db = db.Where("banned_until IS NULL OR banned_until <= ?", time.Now())
+		}
+	}
+
+	if !sq.IncludeDeleted {
+		db = db.Where("deleted_at IS NULL")
+	}
+
+	if sq.OrderBy != "" {
+		order, err := orderClause(&dao.UserModel{}, sq.OrderBy, sq.Sort)
+		if err != nil {
+			return nil, err
+		}
+		db = db.Order(order)
 	}
 
 	if err := db.Limit(sq.Limit).Scan(&result).Error; err != nil {
@@ -79,6 +190,7 @@ func searchUser(ctx context.Context, request mcp.CallToolRequest, sq SearchUserR
 		us.Eich(u)
 		users = append(users, &us)
 	}
+	applyUserMasking(ctx, tool, users)
 
 	return users, nil
 }
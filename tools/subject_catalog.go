@@ -0,0 +1,207 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/mark3labs/mcp-go/mcp"
+	"log"
+	"mcp/server/config"
+	"mcp/server/dao"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const subjectCatalogURI = "subject://catalog"
+
+type SubjectEntry struct {
+	Id      int      `json:"id"`
+	Name    string   `json:"name"`
+	Aliases []string `json:"aliases,omitempty"`
+}
+
+var (
+	subjectCacheMu   sync.Mutex
+	subjectCache     []SubjectEntry
+	subjectCacheTime time.Time
+)
+
+// subjectCatalog 合并配置文件与栏目表中的栏目，结果缓存 10 分钟
+func subjectCatalog() []SubjectEntry {
+	subjectCacheMu.Lock()
+	defer subjectCacheMu.Unlock()
+
+	if subjectCache != nil && time.Since(subjectCacheTime) < 10*time.Minute {
+		return subjectCache
+	}
+
+	byId := make(map[int]*SubjectEntry)
+	var ids []int
+	add := func(id int, name string, aliases ...string) {
+		if e, ok := byId[id]; ok {
+			e.Aliases = append(e.Aliases, aliases...)
+			return
+		}
+		byId[id] = &SubjectEntry{Id: id, Name: name, Aliases: aliases}
+		ids = append(ids, id)
+	}
+
+	var loadErr error
+	cfg := config.Cfg.Catalog
+	if cfg != nil {
+		for _, s := range cfg.Subjects {
+			add(s.Id, s.Name, s.Aliases...)
+		}
+
+		if cfg.SubjectTable != "" {
+			subjects, err := dao.ListSubjects(cfg.SubjectTable)
+			if err != nil {
+				log.Println("load subjects failed, err ", err)
+				loadErr = err
+			}
+			for _, s := range subjects {
+				add(s.Id, s.Title)
+			}
+		}
+	}
+
+	sort.Ints(ids)
+	catalog := make([]SubjectEntry, 0, len(ids))
+	for _, id := range ids {
+		catalog = append(catalog, *byId[id])
+	}
+
+	// 读表失败时不缓存，下次调用重试
+	if loadErr == nil {
+		subjectCache = catalog
+		subjectCacheTime = time.Now()
+	}
+	return catalog
+}
+
+func getSubjectCatalogResource() mcp.Resource {
+	return mcp.NewResource(subjectCatalogURI, "栏目目录",
+		mcp.WithResourceDescription("所有栏目(subject)的 id、名称与别名，查询栏目权益时用来确定 subject_id"),
+		mcp.WithMIMEType("application/json"),
+	)
+}
+
+func readSubjectCatalog(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	data, err := json.Marshal(subjectCatalog())
+	if err != nil {
+		return nil, err
+	}
+
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{URI: subjectCatalogURI, MIMEType: "application/json", Text: string(data)},
+	}, nil
+}
+
+func getResolveSubjectTool() mcp.Tool {
+	tool := mcp.NewTool("resolve_subject",
+		mcp.WithDescription("把栏目名称（如《脱水研报》、早知道）解析为栏目ID，支持模糊匹配。调用 get_user_benefit_records 等需要 subject_id 的工具前，先用本工具确定 ID，不要猜测。"),
+		mcp.WithInputSchema[ResolveSubjectReq](),
+		mcp.WithOutputSchema[[]SubjectMatches](),
+	)
+	return tool
+}
+
+type ResolveSubjectReq struct {
+	Names []string `json:"names" jsonschema_description:"栏目名称列表"`
+	Limit int      `json:"limit,omitempty" jsonschema_description:"每个名称最多返回的候选数，默认 3"`
+}
+
+type SubjectMatch struct {
+	Id    int     `json:"id"`
+	Name  string  `json:"name"`
+	Score float64 `json:"score"`
+}
+
+type SubjectMatches struct {
+	Query   string         `json:"query"`
+	Matches []SubjectMatch `json:"matches"`
+}
+
+func resolveSubject(ctx context.Context, request mcp.CallToolRequest, rr ResolveSubjectReq) ([]SubjectMatches, error) {
+	if rr.Limit <= 0 {
+		rr.Limit = 3
+	}
+
+	catalog := subjectCatalog()
+	var result []SubjectMatches
+	for _, name := range rr.Names {
+		result = append(result, SubjectMatches{Query: name, Matches: matchSubjects(catalog, name, rr.Limit)})
+	}
+
+	return result, nil
+}
+
+// matchSubjects 按名称和别名的相似度排序，过滤掉得分过低的候选
+func matchSubjects(catalog []SubjectEntry, query string, limit int) []SubjectMatch {
+	q := normalizeSubjectName(query)
+	var matches []SubjectMatch
+	for _, e := range catalog {
+		best := subjectSimilarity(q, normalizeSubjectName(e.Name))
+		for _, alias := range e.Aliases {
+			best = max(best, subjectSimilarity(q, normalizeSubjectName(alias)))
+		}
+		if best >= 0.4 {
+			matches = append(matches, SubjectMatch{Id: e.Id, Name: e.Name, Score: best})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+
+	return matches
+}
+
+func normalizeSubjectName(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	return strings.NewReplacer("《", "", "》", "", " ", "", "　", "").Replace(name)
+}
+
+// subjectSimilarity 完全相同为 1；包含关系按长度比例打分；否则使用编辑距离
+func subjectSimilarity(a, b string) float64 {
+	if a == "" || b == "" {
+		return 0
+	}
+	if a == b {
+		return 1
+	}
+
+	ra, rb := []rune(a), []rune(b)
+	longer := max(len(ra), len(rb))
+	if strings.Contains(a, b) || strings.Contains(b, a) {
+		return 0.5 + 0.5*float64(min(len(ra), len(rb)))/float64(longer)
+	}
+
+	return 1 - float64(levenshtein(ra, rb))/float64(longer)
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+
+	return prev[len(b)]
+}
@@ -0,0 +1,172 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/mark3labs/mcp-go/mcp"
+	"gorm.io/gorm/schema"
+	"mcp/server/dao"
+	"slices"
+	"strings"
+	"sync"
+)
+
+const tableSchemaURIPrefix = "schema://"
+
+// queryableTable 可以通过工具查询的表。字段说明、可过滤和可排序的字段来自 dao 结构体的 desc、query、enum 标签，
+// 只有带 desc 标签的字段会公开给模型
+type queryableTable struct {
+	model       any
+	description string
+	tools       []string
+}
+
+var queryableTables = []queryableTable{
+	{&dao.ContentMessage{}, "资讯（快讯、文章）", []string{"search_content_messages"}},
+	{&dao.UserModel{}, "用户", []string{"search_users", "user_stats", "export_query"}},
+	{&dao.ActivityFreeSubject{}, "用户领取的栏目免费权益，每条记录是一次领取", []string{"get_user_benefit_records", "grant_subject_benefit"}},
+}
+
+type ColumnSchema struct {
+	Name        string   `json:"name"`
+	Type        string   `json:"type"`
+	Description string   `json:"description"`
+	Filterable  bool     `json:"filterable,omitempty"`
+	Sortable    bool     `json:"sortable,omitempty"`
+	Enum        []string `json:"enum,omitempty"`
+}
+
+type TableSchema struct {
+	Table        string         `json:"table"`
+	Description  string         `json:"description"`
+	Tools        []string       `json:"tools"`
+	FilterFields []string       `json:"filter_fields"`
+	SortFields   []string       `json:"sort_fields"`
+	Columns      []ColumnSchema `json:"columns"`
+}
+
+var schemaCache sync.Map
+
+// tableSchema 用 gorm 解析 dao 结构体，字段名与实际的列名保持一致
+func tableSchema(table queryableTable) (*TableSchema, error) {
+	s, err := schema.Parse(table.model, &schemaCache, schema.NamingStrategy{})
+	if err != nil {
+		return nil, err
+	}
+
+	ts := &TableSchema{
+		Table:        s.Table,
+		Description:  table.description,
+		Tools:        table.tools,
+		FilterFields: []string{},
+		SortFields:   []string{},
+		Columns:      []ColumnSchema{},
+	}
+	for _, field := range s.Fields {
+		desc, ok := field.Tag.Lookup("desc")
+		if !ok || field.DBName == "" {
+			continue
+		}
+		query := strings.Split(field.Tag.Get("query"), ",")
+		col := ColumnSchema{
+			Name:        field.DBName,
+			Type:        columnType(field),
+			Description: desc,
+			Filterable:  slices.Contains(query, "filter"),
+			Sortable:    slices.Contains(query, "sort"),
+		}
+		if enum := field.Tag.Get("enum"); enum != "" {
+			col.Enum = strings.Split(enum, ",")
+		}
+		if col.Filterable {
+			ts.FilterFields = append(ts.FilterFields, col.Name)
+		}
+		if col.Sortable {
+			ts.SortFields = append(ts.SortFields, col.Name)
+		}
+		ts.Columns = append(ts.Columns, col)
+	}
+
+	return ts, nil
+}
+
+func columnType(field *schema.Field) string {
+	switch field.DataType {
+	case schema.Bool:
+		return "boolean"
+	case schema.Int, schema.Uint:
+		return "integer"
+	case schema.Float:
+		return "number"
+	case schema.Time:
+		return "datetime"
+	default:
+		return "string"
+	}
+}
+
+func findQueryableTable(name string) (queryableTable, bool) {
+	for _, table := range queryableTables {
+		if ts, err := tableSchema(table); err == nil && ts.Table == name {
+			return table, true
+		}
+	}
+
+	return queryableTable{}, false
+}
+
+// orderClause 校验模型传入的排序字段和方向，只允许 schema 中标记为可排序的列，防止拼接任意 SQL
+func orderClause(model any, orderBy, direction string) (string, error) {
+	ts, err := tableSchema(queryableTable{model: model})
+	if err != nil {
+		return "", err
+	}
+
+	column := strings.ToLower(strings.TrimSpace(orderBy))
+	if !slices.Contains(ts.SortFields, column) {
+		return "", fmt.Errorf("order_by %q is not allowed for %s, use one of %s (see %s%s)",
+			orderBy, ts.Table, strings.Join(ts.SortFields, ", "), tableSchemaURIPrefix, ts.Table)
+	}
+	if strings.EqualFold(strings.TrimSpace(direction), "desc") {
+		return column + " desc", nil
+	}
+
+	return column + " asc", nil
+}
+
+func getTableSchemaResources() []mcp.Resource {
+	resources := make([]mcp.Resource, 0, len(queryableTables))
+	for _, table := range queryableTables {
+		ts, err := tableSchema(table)
+		if err != nil {
+			continue
+		}
+		resources = append(resources, mcp.NewResource(tableSchemaURIPrefix+ts.Table, ts.Table+" 表结构",
+			mcp.WithResourceDescription(fmt.Sprintf("%s表的字段说明，以及 %s 可用的过滤字段、排序字段（order_by）和枚举值，不要猜测列名",
+				table.description, strings.Join(table.tools, "、"))),
+			mcp.WithMIMEType("application/json"),
+		))
+	}
+
+	return resources
+}
+
+func readTableSchema(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	table, ok := findQueryableTable(strings.TrimPrefix(request.Params.URI, tableSchemaURIPrefix))
+	if !ok {
+		return nil, fmt.Errorf("unknown table schema %s", request.Params.URI)
+	}
+	ts, err := tableSchema(table)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(ts)
+	if err != nil {
+		return nil, err
+	}
+
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{URI: request.Params.URI, MIMEType: "application/json", Text: string(data)},
+	}, nil
+}
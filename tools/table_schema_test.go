@@ -0,0 +1,33 @@
+package tools
+
+import (
+	"mcp/server/dao"
+	"testing"
+)
+
+func TestOrderClause(t *testing.T) {
+	tests := []struct {
+		model     any
+		orderBy   string
+		direction string
+		want      string
+		wantErr   bool
+	}{
+		{&dao.ContentMessage{}, "created_at", "desc", "created_at desc", false},
+		{&dao.ContentMessage{}, " Like_Count ", "DESC", "like_count desc", false},
+		{&dao.ContentMessage{}, "impact", "", "impact asc", false},
+		{&dao.ContentMessage{}, "id", "sideways", "id asc", false},
+		{&dao.UserModel{}, "last_active_at", "desc", "last_active_at desc", false},
+		{&dao.UserModel{}, "mobile", "asc", "", true},
+		{&dao.ContentMessage{}, "created_at; DROP TABLE users", "desc", "", true},
+		{&dao.ContentMessage{}, "created_at desc, (select 1)", "", "", true},
+		{&dao.ContentMessage{}, "title", "asc", "", true},
+		{&dao.ContentMessage{}, "", "asc", "", true},
+	}
+	for _, tt := range tests {
+		got, err := orderClause(tt.model, tt.orderBy, tt.direction)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("orderClause(%T, %q, %q) = %q, %v, want %q, error=%v", tt.model, tt.orderBy, tt.direction, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -9,6 +9,35 @@ func RegisterTools(s *server.MCPServer) {
 	//s.AddTool(getContentMessagesTool(), mcp.NewTypedToolHandler(getContentMessages))
 	//s.AddTool(getSearchArticleTool(), mcp.NewTypedToolHandler(searchArticle))
 	s.AddTool(getSearchUserTool(), mcp.NewStructuredToolHandler(searchUser))
+	s.AddTool(getLookupUserTool(), mcp.NewStructuredToolHandler(lookupUser))
+	s.AddTool(getUserStatsTool(), mcp.NewStructuredToolHandler(userStats))
+	s.AddTool(getResolveSubjectTool(), mcp.NewStructuredToolHandler(resolveSubject))
 	s.AddTool(getUserBenefitRecordsTool(), mcp.NewTypedToolHandler(getUserBenefitRecords))
+	s.AddTool(getGrantSubjectBenefitTool(), mcp.NewTypedToolHandler(grantSubjectBenefit))
 	s.AddTool(generateCsvTool(), mcp.NewTypedToolHandler(generateCsv))
+	s.AddTool(getCreateUploadLinkTool(), mcp.NewStructuredToolHandler(createUploadLink))
+	s.AddTool(getExportQueryTool(), mcp.NewStructuredToolHandler(exportQuery))
+	s.AddTool(getGenerateChartTool(), mcp.NewStructuredToolHandler(generateChart))
+	s.AddTool(getRenderReportTool(), mcp.NewStructuredToolHandler(renderReport))
+	s.AddTool(getListDocumentsTool(), mcp.NewStructuredToolHandler(listDocuments))
+	s.AddTool(getDocumentLinkTool(), mcp.NewStructuredToolHandler(getDocumentLink))
+	s.AddTool(getDeleteDocumentsTool(), mcp.NewStructuredToolHandler(deleteDocuments))
+	s.AddTool(getRetentionReportTool(), mcp.NewStructuredToolHandler(retentionReport))
+	s.AddTool(getBundleDocumentsTool(), mcp.NewStructuredToolHandler(bundleDocuments))
+}
+
+func RegisterPrompts(s *server.MCPServer) {
+	for _, tpl := range reportTemplates() {
+		s.AddPrompt(reportPrompt(tpl))
+	}
+	s.AddPrompts(analystPrompts()...)
+}
+
+func RegisterResources(s *server.MCPServer) {
+	s.AddResource(getSubjectCatalogResource(), readSubjectCatalog)
+	for _, resource := range getTableSchemaResources() {
+		s.AddResource(resource, readTableSchema)
+	}
+	s.AddResourceTemplate(getArticleResourceTemplate(), readArticleResource)
+	s.AddResourceTemplate(getNewsResourceTemplate(), readNewsResource)
 }
@@ -0,0 +1,159 @@
+package tools
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/mark3labs/mcp-go/mcp"
+	"mcp/server/client"
+	"mcp/server/config"
+	"mcp/server/dao"
+	"mcp/server/storage"
+	"mcp/server/util"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+const uploadPrefix = "uploads/"
+
+func getCreateUploadLinkTool() mcp.Tool {
+	tool := mcp.NewTool("create_upload_link",
+		mcp.WithDescription(`
+生成一个预签名上传链接，让用户把本地文件（用户ID表格、PDF 等）上传给服务器。
+返回的 handle 是文件在对象存储中的路径，上传完成后可以传给其他工具（例如 get_user_benefit_records 的 user_ids_object）。
+上传方式二选一：
+- PUT 方式：对 put_url 发送 HTTP PUT，请求头 Content-Type 必须与 content_type 一致
+- 表单方式：向 post_url 提交 multipart/form-data，带上 form_data 中的全部字段，文件字段名为 file；该方式会强制校验大小和类型。post_url 为空时存储不支持表单方式，只能用 PUT（此时 PUT 同样校验大小和类型）
+`),
+		mcp.WithInputSchema[CreateUploadLinkReq](),
+		mcp.WithOutputSchema[UploadLink](),
+	)
+	return tool
+}
+
+type CreateUploadLinkReq struct {
+	Filename    string `json:"filename" jsonschema_description:"原始文件名，例如 ids.csv"`
+	ContentType string `json:"content_type" jsonschema_description:"文件 MIME 类型，例如 text/csv、application/pdf、application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"`
+}
+
+type UploadLink struct {
+	Handle       string            `json:"handle"`
+	PutURL       string            `json:"put_url"`
+	PostURL      string            `json:"post_url"`
+	FormData     map[string]string `json:"form_data"`
+	ContentType  string            `json:"content_type"`
+	MaxSizeBytes int64             `json:"max_size_bytes"`
+	ExpiresAt    time.Time         `json:"expires_at"`
+}
+
+func uploadConfig() config.UploadConfig {
+	cfg := config.UploadConfig{MaxSizeMB: 50, ExpiryMinutes: 30}
+	if c := config.Cfg.Upload; c != nil {
+		if c.MaxSizeMB > 0 {
+			cfg.MaxSizeMB = c.MaxSizeMB
+		}
+		if c.ExpiryMinutes > 0 {
+			cfg.ExpiryMinutes = c.ExpiryMinutes
+		}
+		cfg.AllowedContentTypes = c.AllowedContentTypes
+	}
+	if len(cfg.AllowedContentTypes) == 0 {
+		cfg.AllowedContentTypes = []string{"text/csv", "text/plain", "application/json"}
+	}
+
+	return cfg
+}
+
+func createUploadLink(ctx context.Context, request mcp.CallToolRequest, ur CreateUploadLinkReq) (*UploadLink, error) {
+	cfg := uploadConfig()
+	contentType := strings.ToLower(strings.TrimSpace(ur.ContentType))
+	if !slices.Contains(cfg.AllowedContentTypes, contentType) {
+		return nil, fmt.Errorf("content_type %q is not allowed, allowed: %s", ur.ContentType, strings.Join(cfg.AllowedContentTypes, ", "))
+	}
+
+	name := safeFileName(ur.Filename)
+	if name == "" {
+		return nil, fmt.Errorf("filename is required")
+	}
+
+	token := make([]byte, 8)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+	handle := fmt.Sprintf("%s%s/%s_%s", uploadPrefix, time.Now().In(util.Loc).Format("20060102"), hex.EncodeToString(token), name)
+
+	expiry := time.Duration(cfg.ExpiryMinutes) * time.Minute
+	maxSize := int64(cfg.MaxSizeMB) << 20
+
+	upload, err := storage.Store.PresignUpload(ctx, handle, expiry, contentType, maxSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return &UploadLink{
+		Handle:       handle,
+		PutURL:       upload.PutURL,
+		PostURL:      upload.PostURL,
+		FormData:     upload.FormData,
+		ContentType:  contentType,
+		MaxSizeBytes: maxSize,
+		ExpiresAt:    time.Now().Add(expiry),
+	}, nil
+}
+
+// checkObjectHandle 在工具读取 handle 前校验：只允许读取上传目录和生成目录下的文件；
+// 生成的文件必须是调用方自己生成的，避免读到其他调用方的导出结果；
+// 对上传的文件还要检查大小和类型，因为 PUT 方式的预签名链接无法在上传时限制大小。
+func checkObjectHandle(ctx context.Context, handle string) error {
+	if strings.Contains(handle, "..") {
+		return fmt.Errorf("invalid object handle %q", handle)
+	}
+	if strings.HasPrefix(handle, generatedPrefix) {
+		var count int64
+		if err := client.Mysql.WithContext(ctx).Model(&dao.GeneratedDocument{}).Scopes(ownedDocuments(ctx)).
+			Where("object_name = ?", handle).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return fmt.Errorf("object handle %q not found in your generated files", handle)
+		}
+		return nil
+	}
+	if !strings.HasPrefix(handle, uploadPrefix) {
+		return fmt.Errorf("object handle %q must start with %s or %s", handle, uploadPrefix, generatedPrefix)
+	}
+
+	info, err := storage.Store.Stat(ctx, handle)
+	if err != nil {
+		return fmt.Errorf("object %s not found, has the file been uploaded? %w", handle, err)
+	}
+
+	cfg := uploadConfig()
+	if maxSize := int64(cfg.MaxSizeMB) << 20; info.Size > maxSize {
+		return fmt.Errorf("object %s is %d bytes, exceeds limit of %d bytes", handle, info.Size, maxSize)
+	}
+	contentType, _, _ := strings.Cut(info.ContentType, ";")
+	if !slices.Contains(cfg.AllowedContentTypes, strings.ToLower(strings.TrimSpace(contentType))) {
+		return fmt.Errorf("object %s has content type %q which is not allowed", handle, info.ContentType)
+	}
+
+	return nil
+}
+
+// safeFileName 只保留文件名部分，去掉路径分隔符和控制字符
+func safeFileName(name string) string {
+	name = path.Base(strings.ReplaceAll(strings.TrimSpace(name), "\\", "/"))
+	if name == "." || name == "/" || name == ".." {
+		return ""
+	}
+
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || strings.ContainsRune(`"'<>|:*?;%`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+}
@@ -2,30 +2,256 @@ package tools
 
 import (
 	"context"
+	"fmt"
 	"github.com/mark3labs/mcp-go/mcp"
+	"gorm.io/gorm"
+	"maps"
+	"math"
 	"mcp/server/client"
 	"mcp/server/dao"
+	"slices"
+	"time"
 )
 
+// benefitExpiryExpr 权益到期时间 = 领取时间 + 免费天数
+const benefitExpiryExpr = "DATE_ADD(created_at, INTERVAL subject_free_days DAY)"
+
 func getUserBenefitRecordsTool() mcp.Tool {
 	tool := mcp.NewTool("get_user_benefit_records",
-		mcp.WithDescription("根据用户ID列表，查询他们是否领取了指定的栏目权限（如《脱水研报》、《早知道》）。脱水研报的id是581，早知道的id是679。"),
-		mcp.WithArray("user_ids", mcp.Required(), mcp.WithNumberItems(mcp.Description("用户ID列表"))),
+		mcp.WithDescription("根据用户ID列表，查询他们是否领取了指定的栏目权限（如《脱水研报》、《早知道》）。栏目ID请先通过 resolve_subject 工具或 subject://catalog 资源获取，不要猜测。返回每条权益的到期时间、是否仍有效、剩余天数，以及按栏目汇总的数量。不传 user_ids 时按栏目查询全部用户，可用于找出即将到期的用户。需要把全部明细导出为文件时使用 export_query。"),
+		mcp.WithArray("user_ids", mcp.WithNumberItems(mcp.Description("用户ID列表"))),
 		mcp.WithArray("subject_ids", mcp.Required(), mcp.WithNumberItems(mcp.Description("栏目id列表"))),
+		mcp.WithString("status", mcp.Enum("active", "expired"), mcp.Description("按状态过滤：active 仍有效，expired 已过期，不填返回全部")),
+		mcp.WithNumber("expiring_within_days", mcp.Description("只返回在未来 N 天内到期的有效权益")),
+		mcp.WithNumber("limit", mcp.Description("返回明细的最大条数，默认 500，最大 5000；汇总数量不受此限制")),
+		mcp.WithString("user_ids_object", mcp.Description("用户ID较多（上千个）时，不要在 user_ids 中逐个列出，改为先用 create_upload_link 让用户上传 CSV 文件，再传入返回的 handle。此时全部明细会写入结果文件并返回下载链接")),
+		mcp.WithString("user_ids_column", mcp.Description("CSV 文件有表头时，用户ID所在的列名，默认取第一列")),
 	)
 	return tool
 }
 
 type QueryUserBenefitRecords struct {
-	UserIds    []int `json:"user_ids"`
-	SubjectIds []int `json:"subject_ids"`
+	UserIds            []int  `json:"user_ids"`
+	SubjectIds         []int  `json:"subject_ids"`
+	Status             string `json:"status"`
+	ExpiringWithinDays int    `json:"expiring_within_days"`
+	Limit              int    `json:"limit"`
+	UserIdsObject      string `json:"user_ids_object"`
+	UserIdsColumn      string `json:"user_ids_column"`
+}
+
+type BenefitRecord struct {
+	dao.ActivityFreeSubject
+	ExpiresAt time.Time `json:"expires_at"`
+	Active    bool      `json:"active"`
+	DaysLeft  int       `json:"days_left"`
+}
+
+type BenefitSummary struct {
+	SubjectId int   `json:"subject_id"`
+	Total     int64 `json:"total"`
+	Active    int64 `json:"active"`
+	Expired   int64 `json:"expired"`
+	// 未来 expiring_within_days 天（未指定时为 7 天）内到期的数量
+	ExpiringSoon int64 `json:"expiring_soon,omitempty"`
+}
+
+type BenefitRecordsResult struct {
+	QueriedUsers int              `json:"queried_users,omitempty"`
+	Summary      []BenefitSummary `json:"summary"`
+	Records      []BenefitRecord  `json:"records"`
+	// 通过 user_ids_object 查询时，全部明细写入该文件
+	FileURL  string `json:"file_url,omitempty"`
+	FileRows int    `json:"file_rows,omitempty"`
 }
 
 func getUserBenefitRecords(ctx context.Context, request mcp.CallToolRequest, args QueryUserBenefitRecords) (*mcp.CallToolResult, error) {
+	if len(args.SubjectIds) == 0 {
+		return mcp.NewToolResultError("subject_ids is required"), nil
+	}
+	if args.Limit <= 0 {
+		args.Limit = 500
+	}
+	if args.Limit > 5000 {
+		args.Limit = 5000
+	}
+
+	userIds, err := benefitUserIds(ctx, args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	batches := benefitBatches(userIds)
+
+	now := time.Now()
+	// 通过文件传入用户时，明细写入结果文件，不再受 limit 限制
+	exportAll := args.UserIdsObject != ""
+
 	var records []dao.ActivityFreeSubject
-	if err := client.Mysql.Model(&dao.ActivityFreeSubject{}).Find(&records, "user_id in (?) and subject_id in (?)", args.UserIds, args.SubjectIds).Error; err != nil {
+	for _, batch := range batches {
+		var batchRecords []dao.ActivityFreeSubject
+		db := client.Mysql.WithContext(ctx).Model(&dao.ActivityFreeSubject{}).Scopes(benefitScope(args, batch, now)).Order(benefitExpiryExpr)
+		if !exportAll {
+			// 每批都取前 limit 条，合并排序后再截断，才是全部用户中最早到期的记录
+			db = db.Limit(args.Limit)
+		}
+		if err := db.Find(&batchRecords).Error; err != nil {
+			return nil, err
+		}
+		records = append(records, batchRecords...)
+	}
+	sortByExpiry(records)
+
+	summary, err := benefitSummaries(ctx, args, batches, now)
+	if err != nil {
 		return nil, err
 	}
 
-	return mcp.NewToolResultStructuredOnly(records), nil
+	result := BenefitRecordsResult{QueriedUsers: len(userIds), Summary: summary, Records: make([]BenefitRecord, 0, min(len(records), args.Limit))}
+	for _, r := range records {
+		if len(result.Records) >= args.Limit {
+			break
+		}
+		result.Records = append(result.Records, newBenefitRecord(r, now))
+	}
+
+	if exportAll {
+		all := make([]BenefitRecord, 0, len(records))
+		for _, r := range records {
+			all = append(all, newBenefitRecord(r, now))
+		}
+		export, err := writeExport(ctx, "benefit_records", "csv", "", all, linkExpiry(0))
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Upload failed: %v", err)), nil
+		}
+		result.FileURL = export.URL
+		result.FileRows = export.Rows
+	}
+
+	return mcp.NewToolResultStructuredOnly(result), nil
+}
+
+// benefitSummaries 按栏目汇总每个批次的领取情况并合并
+func benefitSummaries(ctx context.Context, args QueryUserBenefitRecords, batches [][]int, now time.Time) ([]BenefitSummary, error) {
+	soon := now.AddDate(0, 0, max(args.ExpiringWithinDays, 7))
+	summaryBySubject := make(map[int]*BenefitSummary)
+	for _, batch := range batches {
+		var summary []BenefitSummary
+		if err := client.Mysql.WithContext(ctx).Model(&dao.ActivityFreeSubject{}).Scopes(benefitScope(args, batch, now)).
+			Select("subject_id, COUNT(*) AS total, "+
+				"IFNULL(SUM("+benefitExpiryExpr+" > ?), 0) AS active, "+
+				"IFNULL(SUM("+benefitExpiryExpr+" <= ?), 0) AS expired, "+
+				"IFNULL(SUM("+benefitExpiryExpr+" between ? and ?), 0) AS expiring_soon", now, now, now, soon).
+			Group("subject_id").Scan(&summary).Error; err != nil {
+			return nil, err
+		}
+		for _, sm := range summary {
+			if acc, ok := summaryBySubject[sm.SubjectId]; ok {
+				acc.Total += sm.Total
+				acc.Active += sm.Active
+				acc.Expired += sm.Expired
+				acc.ExpiringSoon += sm.ExpiringSoon
+			} else {
+				summaryBySubject[sm.SubjectId] = &sm
+			}
+		}
+	}
+
+	result := make([]BenefitSummary, 0, len(summaryBySubject))
+	for _, subjectId := range slices.Sorted(maps.Keys(summaryBySubject)) {
+		result = append(result, *summaryBySubject[subjectId])
+	}
+
+	return result, nil
+}
+
+// benefitUserIds 合并 user_ids 与 user_ids_object 中的用户
+func benefitUserIds(ctx context.Context, args QueryUserBenefitRecords) ([]int, error) {
+	userIds := args.UserIds
+	if args.UserIdsObject != "" {
+		ids, err := loadIdsFromObject(ctx, args.UserIdsObject, args.UserIdsColumn)
+		if err != nil {
+			return nil, fmt.Errorf("load user_ids_object failed: %w", err)
+		}
+		userIds = uniqueInts(append(userIds, ids...))
+	}
+
+	return userIds, nil
+}
+
+// benefitBatches 不传用户时只查一批；否则按批拆分，避免 IN (...) 超过 MySQL 占位符上限
+func benefitBatches(userIds []int) [][]int {
+	if len(userIds) == 0 {
+		return [][]int{nil}
+	}
+
+	return slices.Collect(slices.Chunk(userIds, idQueryBatchSize))
+}
+
+// queryAllBenefitRecords 查询全部明细（最多 maxRows 条），供 export_query 导出
+func queryAllBenefitRecords(ctx context.Context, args QueryUserBenefitRecords, maxRows int) ([]BenefitRecord, error) {
+	if len(args.SubjectIds) == 0 {
+		return nil, fmt.Errorf("subject_ids is required")
+	}
+	userIds, err := benefitUserIds(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var all []dao.ActivityFreeSubject
+	for _, batch := range benefitBatches(userIds) {
+		var batchRecords []dao.ActivityFreeSubject
+		if err := client.Mysql.WithContext(ctx).Model(&dao.ActivityFreeSubject{}).
+			Scopes(benefitScope(args, batch, now)).
+			Order(benefitExpiryExpr).
+			Limit(maxRows).
+			Find(&batchRecords).Error; err != nil {
+			return nil, err
+		}
+		all = append(all, batchRecords...)
+	}
+	sortByExpiry(all)
+
+	records := make([]BenefitRecord, 0, min(len(all), maxRows))
+	for _, r := range all[:min(len(all), maxRows)] {
+		records = append(records, newBenefitRecord(r, now))
+	}
+
+	return records, nil
+}
+
+// sortByExpiry 分批查询的结果只在批内按到期时间有序，合并后需要整体重新排序
+func sortByExpiry(records []dao.ActivityFreeSubject) {
+	slices.SortStableFunc(records, func(a, b dao.ActivityFreeSubject) int {
+		return a.CreatedAt.AddDate(0, 0, a.SubjectFreeDays).Compare(b.CreatedAt.AddDate(0, 0, b.SubjectFreeDays))
+	})
+}
+
+func benefitScope(args QueryUserBenefitRecords, userIds []int, now time.Time) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		db = db.Where("subject_id in (?)", args.SubjectIds)
+		if len(userIds) > 0 {
+			db = db.Where("user_id in (?)", userIds)
+		}
+		switch args.Status {
+		case "active":
+			db = db.Where(benefitExpiryExpr+" > ?", now)
+		case "expired":
+			db = db.Where(benefitExpiryExpr+" <= ?", now)
+		}
+		if args.ExpiringWithinDays > 0 {
+			db = db.Where(benefitExpiryExpr+" between ? and ?", now, now.AddDate(0, 0, args.ExpiringWithinDays))
+		}
+		return db
+	}
+}
+
+func newBenefitRecord(r dao.ActivityFreeSubject, now time.Time) BenefitRecord {
+	expiresAt := r.CreatedAt.AddDate(0, 0, r.SubjectFreeDays)
+	br := BenefitRecord{ActivityFreeSubject: r, ExpiresAt: expiresAt, Active: expiresAt.After(now)}
+	if br.Active {
+		br.DaysLeft = int(math.Ceil(expiresAt.Sub(now).Hours() / 24))
+	}
+
+	return br
 }
@@ -0,0 +1,245 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"github.com/mark3labs/mcp-go/mcp"
+	"mcp/server/client"
+	"mcp/server/dao"
+	"mcp/server/util"
+	"time"
+)
+
+func getUserStatsTool() mcp.Tool {
+	tool := mcp.NewTool("user_stats",
+		mcp.WithDescription(`
+用户增长统计工具（MySQL 聚合，数据来自 user_users 表）：
+- new_users：按天/周/月统计新增注册用户数（基于 created_at）
+- active_users：按天/周/月估算 DAU/WAU/MAU（基于 last_active_at，只记录最后一次活跃，因此是下限估计）
+- retention：按注册时间分组的留存队列，第 N 期留存 = 注册后 N 个周期仍有活跃记录的用户数；尚未到达的期数返回 null
+可通过 group_by 按平台(platform_name)或渠道(xgb_channel)拆分。需要用户明细时请使用 search_users。
+`),
+		mcp.WithInputSchema[UserStatsReq](),
+		mcp.WithOutputSchema[UserStatsResult](),
+	)
+	return tool
+}
+
+type UserStatsReq struct {
+	Metric           string     `json:"metric" jsonschema:"enum=new_users,enum=active_users,enum=retention" jsonschema_description:"统计指标：new_users 新增用户，active_users 活跃用户，retention 留存队列"`
+	Granularity      string     `json:"granularity,omitempty" jsonschema:"enum=day,enum=week,enum=month" jsonschema_description:"时间粒度：day、week（周一开始）、month，默认 day"`
+	StartTime        *time.Time `json:"start_time,omitempty" jsonschema_description:"统计开始时间, RFC3339 timestamp, e.g. 2024-12-01T00:00:00+08:00，默认最近30天"`
+	EndTime          *time.Time `json:"end_time,omitempty" jsonschema_description:"统计结束时间, RFC3339 timestamp, e.g. 2024-12-31T23:59:59+08:00，默认当前时间"`
+	GroupBy          string     `json:"group_by,omitempty" jsonschema:"enum=platform_name,enum=xgb_channel" jsonschema_description:"拆分维度，可选 platform_name 或 xgb_channel，不填则不拆分"`
+	RetentionPeriods int        `json:"retention_periods,omitempty" jsonschema_description:"retention 指标计算的期数，默认 7，最大 30"`
+}
+
+type UserStatsPoint struct {
+	Period string `json:"period"`
+	Group  string `json:"group,omitempty"`
+	Count  int64  `json:"count"`
+}
+
+type RetentionCohort struct {
+	Cohort string `json:"cohort"`
+	Group  string `json:"group,omitempty"`
+	Size   int64  `json:"size"`
+	// 第 N 期还没有到达（队列中最晚注册的用户注册后还不满 N 个周期）时为 null，不能当作 0 留存
+	Retained []*int64   `json:"retained"`
+	Rates    []*float64 `json:"rates"`
+}
+
+type UserStatsResult struct {
+	Metric      string            `json:"metric"`
+	Granularity string            `json:"granularity"`
+	GroupBy     string            `json:"group_by,omitempty"`
+	StartTime   time.Time         `json:"start_time"`
+	EndTime     time.Time         `json:"end_time"`
+	Series      []UserStatsPoint  `json:"series,omitempty"`
+	Cohorts     []RetentionCohort `json:"cohorts,omitempty"`
+}
+
+// 数据库中的时间为 UTC（连接参数 loc=UTC，见 client.InitMysql），分桶前先转换到北京时间
+func periodExpr(column, granularity string) string {
+	local := fmt.Sprintf("CONVERT_TZ(%s, '+00:00', '+08:00')", column)
+	switch granularity {
+	case "week":
+		return fmt.Sprintf("DATE_FORMAT(DATE_SUB(DATE(%s), INTERVAL WEEKDAY(%s) DAY), '%%Y-%%m-%%d')", local, local)
+	case "month":
+		return fmt.Sprintf("DATE_FORMAT(%s, '%%Y-%%m')", local)
+	default:
+		return fmt.Sprintf("DATE_FORMAT(%s, '%%Y-%%m-%%d')", local)
+	}
+}
+
+func intervalUnit(granularity string) string {
+	switch granularity {
+	case "week":
+		return "WEEK"
+	case "month":
+		return "MONTH"
+	default:
+		return "DAY"
+	}
+}
+
+func userStats(ctx context.Context, request mcp.CallToolRequest, req UserStatsReq) (*UserStatsResult, error) {
+	switch req.Granularity {
+	case "":
+		req.Granularity = "day"
+	case "day", "week", "month":
+	default:
+		return nil, fmt.Errorf("unsupported granularity: %s", req.Granularity)
+	}
+
+	switch req.GroupBy {
+	case "", "platform_name", "xgb_channel":
+	default:
+		return nil, fmt.Errorf("unsupported group_by: %s", req.GroupBy)
+	}
+
+	endTime := time.Now().In(util.Loc)
+	if req.EndTime != nil {
+		endTime = *req.EndTime
+	}
+	startTime := endTime.AddDate(0, 0, -30)
+	if req.StartTime != nil {
+		startTime = *req.StartTime
+	}
+
+	result := &UserStatsResult{
+		Metric:      req.Metric,
+		Granularity: req.Granularity,
+		GroupBy:     req.GroupBy,
+		StartTime:   startTime,
+		EndTime:     endTime,
+	}
+
+	var err error
+	switch req.Metric {
+	case "new_users":
+		result.Series, err = countUsersByPeriod(ctx, "created_at", req.Granularity, req.GroupBy, startTime, endTime)
+	case "active_users":
+		result.Series, err = countUsersByPeriod(ctx, "last_active_at", req.Granularity, req.GroupBy, startTime, endTime)
+	case "retention":
+		result.Cohorts, err = retentionCohorts(ctx, req.Granularity, req.GroupBy, req.RetentionPeriods, startTime, endTime)
+	default:
+		return nil, fmt.Errorf("unsupported metric: %s", req.Metric)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func countUsersByPeriod(ctx context.Context, column, granularity, groupBy string, startTime, endTime time.Time) ([]UserStatsPoint, error) {
+	selects := periodExpr(column, granularity) + " AS period, COUNT(*) AS count"
+	groups := "period"
+	if groupBy != "" {
+		selects += ", IFNULL(" + groupBy + ", '') AS `group`"
+		groups += ", `group`"
+	}
+
+	var points []UserStatsPoint
+	err := client.Mysql.WithContext(ctx).Model(&dao.UserModel{}).
+		Select(selects).
+		Where(column+" between ? and ?", startTime, endTime).
+		Where("deleted_at IS NULL").
+		Group(groups).
+		Order(groups).
+		Scan(&points).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return points, nil
+}
+
+func retentionCohorts(ctx context.Context, granularity, groupBy string, periods int, startTime, endTime time.Time) ([]RetentionCohort, error) {
+	if periods <= 0 {
+		periods = 7
+	}
+	if periods > 30 {
+		periods = 30
+	}
+
+	selects := periodExpr("created_at", granularity) + " AS cohort, COUNT(*) AS size"
+	for i := 1; i <= periods; i++ {
+		selects += fmt.Sprintf(", IFNULL(SUM(last_active_at >= DATE_ADD(created_at, INTERVAL %d %s)), 0) AS r%d", i, intervalUnit(granularity), i)
+	}
+	groups := "cohort"
+	if groupBy != "" {
+		selects += ", IFNULL(" + groupBy + ", '') AS `group`"
+		groups += ", `group`"
+	}
+
+	rows, err := client.Mysql.WithContext(ctx).Model(&dao.UserModel{}).
+		Select(selects).
+		Where("created_at between ? and ?", startTime, endTime).
+		Where("deleted_at IS NULL").
+		Group(groups).
+		Order(groups).
+		Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	now := time.Now()
+	var cohorts []RetentionCohort
+	for rows.Next() {
+		c := RetentionCohort{Retained: make([]*int64, periods), Rates: make([]*float64, periods)}
+		retained := make([]int64, periods)
+		dest := []any{&c.Cohort, &c.Size}
+		for i := range retained {
+			dest = append(dest, &retained[i])
+		}
+		if groupBy != "" {
+			dest = append(dest, &c.Group)
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+
+		start, err := cohortStart(c.Cohort, granularity)
+		if err != nil {
+			return nil, err
+		}
+		for i, n := range retained {
+			// 第 i+1 期要等队列中最晚注册的用户也满 i+1 个周期，即队列结束后再过 i+1 个周期
+			if addPeriods(start, granularity, i+2).After(now) {
+				break
+			}
+			c.Retained[i] = &n
+			if c.Size > 0 {
+				rate := float64(n) / float64(c.Size)
+				c.Rates[i] = &rate
+			}
+		}
+		cohorts = append(cohorts, c)
+	}
+
+	return cohorts, rows.Err()
+}
+
+// cohortStart 解析 periodExpr 生成的周期标签，返回该周期开始的北京时间
+func cohortStart(label, granularity string) (time.Time, error) {
+	layout := time.DateOnly
+	if granularity == "month" {
+		layout = "2006-01"
+	}
+
+	return time.ParseInLocation(layout, label, util.Loc)
+}
+
+func addPeriods(t time.Time, granularity string, n int) time.Time {
+	switch granularity {
+	case "week":
+		return t.AddDate(0, 0, 7*n)
+	case "month":
+		return t.AddDate(0, n, 0)
+	default:
+		return t.AddDate(0, 0, n)
+	}
+}
@@ -0,0 +1,235 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// ContentError 指出内容中出错的位置，返回给模型以便修正后重试
+type ContentError struct {
+	Line   int
+	Column int
+	Msg    string
+}
+
+func (e *ContentError) Error() string {
+	if e.Column > 0 {
+		return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Msg)
+	}
+
+	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+}
+
+// validateCSV 检查 CSV 能否解析，且每一行的列数与表头一致
+func validateCSV(content string) error {
+	reader := csv.NewReader(strings.NewReader(strings.TrimPrefix(content, "\uFEFF")))
+	header := -1
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		var pe *csv.ParseError
+		if errors.As(err, &pe) {
+			if errors.Is(pe.Err, csv.ErrFieldCount) {
+				return &ContentError{Line: pe.StartLine, Msg: fmt.Sprintf("expected %d fields as in header, got %d", header, len(record))}
+			}
+			return &ContentError{Line: pe.Line, Column: pe.Column, Msg: pe.Err.Error()}
+		}
+		if err != nil {
+			return err
+		}
+
+		if header < 0 {
+			header = len(record)
+		}
+	}
+	if header < 0 {
+		return &ContentError{Line: 1, Msg: "content is empty"}
+	}
+
+	return nil
+}
+
+// validateJSON 检查 JSON 能否解析，语法错误时换算出行号和列号
+func validateJSON(content string) error {
+	dec := json.NewDecoder(strings.NewReader(content))
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		var se *json.SyntaxError
+		var te *json.UnmarshalTypeError
+		switch {
+		case errors.As(err, &se):
+			line, col := offsetToLineColumn(content, se.Offset)
+			return &ContentError{Line: line, Column: col, Msg: se.Error()}
+		case errors.As(err, &te):
+			line, col := offsetToLineColumn(content, te.Offset)
+			return &ContentError{Line: line, Column: col, Msg: te.Error()}
+		case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+			line, col := offsetToLineColumn(content, int64(len(content)))
+			return &ContentError{Line: line, Column: col, Msg: "unexpected end of JSON input"}
+		default:
+			return err
+		}
+	}
+
+	// 一个值之后不能再有其他内容
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		line, col := offsetToLineColumn(content, dec.InputOffset())
+		return &ContentError{Line: line, Column: col, Msg: "unexpected content after top-level JSON value"}
+	}
+
+	return nil
+}
+
+func offsetToLineColumn(content string, offset int64) (int, int) {
+	if offset > int64(len(content)) {
+		offset = int64(len(content))
+	}
+	before := content[:offset]
+	line := strings.Count(before, "\n") + 1
+	col := len([]rune(before[strings.LastIndex(before, "\n")+1:]))
+	if col == 0 {
+		col = 1
+	}
+
+	return line, col
+}
+
+var codeFenceRe = regexp.MustCompile("(?s)^\\s*```[a-zA-Z]*\\s*\\n(.*?)\\n?```\\s*$")
+
+// stripCodeFence 去掉模型常常包在内容外面的 ``` 代码块标记
+func stripCodeFence(content string) (string, bool) {
+	if m := codeFenceRe.FindStringSubmatch(content); m != nil {
+		return m[1], true
+	}
+
+	return content, false
+}
+
+// repairCSV 去掉代码块标记；列数不足的行在末尾补空列，列数超出时补充表头列名
+func repairCSV(content string) (string, []string, error) {
+	var fixes []string
+	content, fenced := stripCodeFence(content)
+	if fenced {
+		fixes = append(fixes, "removed markdown code fence")
+	}
+
+	reader := csv.NewReader(strings.NewReader(strings.TrimPrefix(content, "\uFEFF")))
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		// 引号错误无法可靠修复，返回具体位置
+		var pe *csv.ParseError
+		if errors.As(err, &pe) {
+			return "", nil, &ContentError{Line: pe.Line, Column: pe.Column, Msg: pe.Err.Error()}
+		}
+		return "", nil, err
+	}
+	if len(records) == 0 {
+		return "", nil, &ContentError{Line: 1, Msg: "content is empty"}
+	}
+
+	width := 0
+	for _, r := range records {
+		width = max(width, len(r))
+	}
+	if extra := width - len(records[0]); extra > 0 {
+		for i := len(records[0]); i < width; i++ {
+			records[0] = append(records[0], fmt.Sprintf("column_%d", i+1))
+		}
+		fixes = append(fixes, fmt.Sprintf("added %d header columns for rows with extra fields", extra))
+	}
+	padded := 0
+	for i, r := range records[1:] {
+		if len(r) < width {
+			records[i+1] = append(r, make([]string, width-len(r))...)
+			padded++
+		}
+	}
+	if padded > 0 {
+		fixes = append(fixes, fmt.Sprintf("padded %d short rows with empty fields", padded))
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.WriteAll(records); err != nil {
+		return "", nil, err
+	}
+
+	return buf.String(), fixes, nil
+}
+
+// repairJSON 去掉代码块标记和多余的尾逗号；多行 JSON 对象（NDJSON）合并成数组
+func repairJSON(content string) (string, []string) {
+	var fixes []string
+	content, fenced := stripCodeFence(content)
+	if fenced {
+		fixes = append(fixes, "removed markdown code fence")
+	}
+	content = strings.TrimSpace(strings.TrimPrefix(content, "\uFEFF"))
+	if validateJSON(content) == nil {
+		return content, fixes
+	}
+
+	if fixed := removeTrailingCommas(content); fixed != content {
+		content = fixed
+		fixes = append(fixes, "removed trailing commas")
+	}
+
+	if validateJSON(content) != nil {
+		dec := json.NewDecoder(strings.NewReader(content))
+		var items []json.RawMessage
+		for {
+			var item json.RawMessage
+			if err := dec.Decode(&item); err != nil {
+				if errors.Is(err, io.EOF) && len(items) > 1 {
+					data, _ := json.MarshalIndent(items, "", "  ")
+					content = string(data)
+					fixes = append(fixes, fmt.Sprintf("wrapped %d concatenated JSON values into an array", len(items)))
+				}
+				break
+			}
+			items = append(items, item)
+		}
+	}
+
+	return content, fixes
+}
+
+// removeTrailingCommas 删除 } 或 ] 前多余的逗号，跳过字符串字面量，"a,]" 这样的字符串内容保持不变
+func removeTrailingCommas(content string) string {
+	var sb strings.Builder
+	inString, escaped := false, false
+	for i := 0; i < len(content); i++ {
+		c := content[i]
+		switch {
+		case inString:
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+		case c == '"':
+			inString = true
+		case c == ',':
+			next := strings.TrimLeft(content[i+1:], " \t\r\n")
+			if next != "" && (next[0] == '}' || next[0] == ']') {
+				continue
+			}
+		}
+		sb.WriteByte(c)
+	}
+
+	return sb.String()
+}
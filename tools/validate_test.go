@@ -0,0 +1,24 @@
+package tools
+
+import "testing"
+
+func TestRemoveTrailingCommas(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`{"a": 1,}`, `{"a": 1}`},
+		{`[1, 2, 3 , ]`, `[1, 2, 3  ]`},
+		{"{\"a\": [1,\n\t],\n}", "{\"a\": [1\n\t]\n}"},
+		{`{"a": "x,}", "b": ",]"}`, `{"a": "x,}", "b": ",]"}`},
+		{`{"a": "say \",}\"",}`, `{"a": "say \",}\""}`},
+		{`{"a": "c:\\",}`, `{"a": "c:\\"}`},
+		{`[1,2]`, `[1,2]`},
+		{`[1,`, `[1,`},
+	}
+	for _, tt := range tests {
+		if got := removeTrailingCommas(tt.input); got != tt.want {
+			t.Errorf("removeTrailingCommas(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,334 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/xuri/excelize/v2"
+	"io"
+	"iter"
+	"mcp/server/util"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const xlsxMimeType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+// parseTable 把 CSV 或 JSON 数组解析成表头和数据行。
+// JSON 数组的元素可以是对象（按键首次出现的顺序生成表头）或数组（第一行为表头）。
+func parseTable(content string) ([]string, [][]any, error) {
+	trimmed := strings.TrimSpace(content)
+	if strings.HasPrefix(trimmed, "[") {
+		return parseJSONTable(trimmed)
+	}
+
+	reader := csv.NewReader(strings.NewReader(strings.TrimPrefix(content, "\uFEFF")))
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil, errors.New("content is empty")
+	}
+
+	rows := make([][]any, 0, len(records)-1)
+	for _, r := range records[1:] {
+		row := make([]any, len(r))
+		for i, v := range r {
+			row[i] = v
+		}
+		rows = append(rows, row)
+	}
+
+	return records[0], rows, nil
+}
+
+func parseJSONTable(content string) ([]string, [][]any, error) {
+	var items []json.RawMessage
+	if err := json.Unmarshal([]byte(content), &items); err != nil {
+		return nil, nil, err
+	}
+	if len(items) == 0 {
+		return nil, nil, errors.New("content is an empty array")
+	}
+
+	// 元素是数组时，第一个元素作为表头
+	if strings.HasPrefix(strings.TrimSpace(string(items[0])), "[") {
+		var header []string
+		if err := json.Unmarshal(items[0], &header); err != nil {
+			return nil, nil, fmt.Errorf("first row must be an array of column names: %w", err)
+		}
+		rows := make([][]any, 0, len(items)-1)
+		for _, item := range items[1:] {
+			var row []any
+			if err := decodeJSON(item, &row); err != nil {
+				return nil, nil, err
+			}
+			rows = append(rows, row)
+		}
+		return header, rows, nil
+	}
+
+	var header []string
+	index := make(map[string]int)
+	objects := make([]map[string]any, 0, len(items))
+	for _, item := range items {
+		keys, err := objectKeys(item)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, k := range keys {
+			if _, ok := index[k]; !ok {
+				index[k] = len(header)
+				header = append(header, k)
+			}
+		}
+
+		var obj map[string]any
+		if err := decodeJSON(item, &obj); err != nil {
+			return nil, nil, err
+		}
+		objects = append(objects, obj)
+	}
+
+	rows := make([][]any, 0, len(objects))
+	for _, obj := range objects {
+		row := make([]any, len(header))
+		for k, v := range obj {
+			row[index[k]] = v
+		}
+		rows = append(rows, row)
+	}
+
+	return header, rows, nil
+}
+
+// objectKeys 按原始顺序返回 JSON 对象的键
+func objectKeys(raw json.RawMessage) ([]string, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if t, err := dec.Token(); err != nil || t != json.Delim('{') {
+		return nil, errors.New("array elements must be objects or arrays")
+	}
+
+	var keys []string
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, t.(string))
+
+		var skip json.RawMessage
+		if err := dec.Decode(&skip); err != nil {
+			return nil, err
+		}
+	}
+
+	return keys, nil
+}
+
+func decodeJSON(raw json.RawMessage, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+// sliceRows 把内存中的数据行包装成 writeCSV、writeXlsx 需要的逐行迭代器
+func sliceRows(rows [][]any) iter.Seq2[[]any, error] {
+	return func(yield func([]any, error) bool) {
+		for _, row := range rows {
+			if !yield(row, nil) {
+				return
+			}
+		}
+	}
+}
+
+// buildXlsx 生成带表头、类型化单元格和列宽的工作簿
+func buildXlsx(header []string, rows [][]any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := writeXlsx(&buf, header, sliceRows(rows)); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// writeXlsx 把工作簿直接写入 w，导出大文件时配合 DocumentWriter 边生成边上传。
+// rows 会被遍历两次：第一次计算列宽，第二次逐行写入，不在内存中保留全部单元格
+func writeXlsx(w io.Writer, header []string, rows iter.Seq2[[]any, error]) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	sheet := f.GetSheetName(0)
+	headerStyle, err := f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{Bold: true},
+		Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"#D9E1F2"}},
+	})
+	if err != nil {
+		return err
+	}
+	dateStyle, err := f.NewStyle(&excelize.Style{NumFmt: 14})
+	if err != nil {
+		return err
+	}
+	dateTimeStyle, err := f.NewStyle(&excelize.Style{NumFmt: 22})
+	if err != nil {
+		return err
+	}
+
+	sw, err := f.NewStreamWriter(sheet)
+	if err != nil {
+		return err
+	}
+
+	// 流式写入要求先设置列宽和冻结窗格，再写行，所以先遍历一遍计算列宽
+	widths := make([]int, len(header))
+	headerRow := make([]any, len(header))
+	for i, h := range header {
+		headerRow[i] = excelize.Cell{StyleID: headerStyle, Value: h}
+		widths[i] = displayWidth(h)
+	}
+
+	for row, err := range rows {
+		if err != nil {
+			return err
+		}
+		for i, v := range row[:min(len(row), len(widths))] {
+			_, _, text := xlsxCell(v, dateStyle, dateTimeStyle)
+			widths[i] = max(widths[i], displayWidth(text))
+		}
+	}
+
+	for i, width := range widths {
+		if err := sw.SetColWidth(i+1, i+1, float64(min(max(width, 6), 60)+2)); err != nil {
+			return err
+		}
+	}
+	if err := sw.SetPanes(&excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"}); err != nil {
+		return err
+	}
+
+	if err := sw.SetRow("A1", headerRow); err != nil {
+		return err
+	}
+	r := 2
+	for row, err := range rows {
+		if err != nil {
+			return err
+		}
+		cells := make([]any, len(row))
+		for i, v := range row {
+			value, style, _ := xlsxCell(v, dateStyle, dateTimeStyle)
+			cells[i] = excelize.Cell{StyleID: style, Value: value}
+		}
+		cell, _ := excelize.CoordinatesToCellName(1, r)
+		if err := sw.SetRow(cell, cells); err != nil {
+			return err
+		}
+		r++
+	}
+	if err := sw.Flush(); err != nil {
+		return err
+	}
+
+	_, err = f.WriteTo(w)
+	return err
+}
+
+var cellDateLayouts = []struct {
+	layout   string
+	dateOnly bool
+}{
+	{time.DateTime, false},
+	{time.RFC3339, false},
+	{"2006/01/02 15:04:05", false},
+	{time.DateOnly, true},
+	{"2006/01/02", true},
+}
+
+// xlsxCell 把单元格值转换为数字或日期；长数字串（手机号、ID）和前导 0 的数字保持文本，避免精度丢失
+func xlsxCell(v any, dateStyle, dateTimeStyle int) (any, int, string) {
+	var s string
+	switch val := v.(type) {
+	case nil:
+		return nil, 0, ""
+	case json.Number:
+		s = val.String()
+	case string:
+		s = val
+	case bool:
+		return val, 0, strconv.FormatBool(val)
+	case []any, map[string]any:
+		data, _ := json.Marshal(val)
+		return string(data), 0, string(data)
+	default:
+		s = fmt.Sprint(val)
+	}
+
+	trimmed := strings.TrimSpace(s)
+	if isPlainNumber(trimmed) {
+		if f, err := strconv.ParseFloat(trimmed, 64); err == nil {
+			return f, 0, trimmed
+		}
+	}
+	for _, l := range cellDateLayouts {
+		if t, err := time.ParseInLocation(l.layout, trimmed, util.Loc); err == nil {
+			if l.dateOnly {
+				return t, dateStyle, trimmed
+			}
+			return t.In(util.Loc), dateTimeStyle, trimmed
+		}
+	}
+
+	return s, 0, s
+}
+
+func isPlainNumber(s string) bool {
+	if s == "" || len(s) > 15 {
+		return false
+	}
+	digits := strings.TrimPrefix(s, "-")
+	if len(digits) > 1 && digits[0] == '0' && digits[1] != '.' {
+		return false
+	}
+	// 超过 10 位的整数多半是手机号或 ID
+	if !strings.Contains(digits, ".") && len(digits) > 10 {
+		return false
+	}
+	for _, r := range digits {
+		if (r < '0' || r > '9') && r != '.' {
+			return false
+		}
+	}
+
+	return digits != "" && digits != "."
+}
+
+// displayWidth 估算显示宽度，中文等宽字符按 2 计算
+func displayWidth(s string) int {
+	w := 0
+	for _, r := range s {
+		if utf8.RuneLen(r) > 1 {
+			w += 2
+		} else {
+			w++
+		}
+	}
+
+	return w
+}
+
+// withUTF8BOM 给 CSV 加上 BOM，Excel 打开时才能正确识别 UTF-8 中文
+func withUTF8BOM(content string) string {
+	if strings.HasPrefix(content, "\uFEFF") {
+		return content
+	}
+
+	return "\uFEFF" + content
+}
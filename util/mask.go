@@ -0,0 +1,76 @@
+package util
+
+import (
+	"regexp"
+	"strings"
+)
+
+// longDigitsRe 7 位以上的连续数字，多半是手机号
+var longDigitsRe = regexp.MustCompile(`\d{7,}`)
+
+// MaskMobile 保留前 3 位和后 4 位，例如 138****1234
+func MaskMobile(mobile string) string {
+	r := []rune(mobile)
+	if len(r) == 0 {
+		return ""
+	}
+	if len(r) < 8 {
+		return maskRunes(r, 0, len(r)-2)
+	}
+
+	return maskRunes(r, 3, len(r)-4)
+}
+
+// MaskEmail 只保留用户名首字符和域名，例如 a***@domain.com
+func MaskEmail(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at <= 0 {
+		return MaskName(email)
+	}
+
+	name := []rune(email[:at])
+	return string(name[0]) + "***" + email[at:]
+}
+
+// MaskName 只保留第一个字，例如 张**
+func MaskName(name string) string {
+	r := []rune(name)
+	if len(r) == 0 {
+		return ""
+	}
+	if len(r) == 1 {
+		return "*"
+	}
+
+	return maskRunes(r, 1, len(r))
+}
+
+// MaskHandle 用户名、昵称常常直接使用手机号、邮箱或真实姓名：邮箱和其中的长数字按对应规则脱敏，
+// 其余只保留首尾字符，例如 a****f、张*
+func MaskHandle(handle string) string {
+	if strings.Contains(handle, "@") {
+		return MaskEmail(handle)
+	}
+	if longDigitsRe.MatchString(handle) {
+		return longDigitsRe.ReplaceAllStringFunc(handle, MaskMobile)
+	}
+
+	r := []rune(handle)
+	if len(r) <= 3 {
+		return MaskName(handle)
+	}
+
+	return maskRunes(r, 1, len(r)-1)
+}
+
+// maskRunes 将 [start, end) 区间的字符替换为 *
+func maskRunes(r []rune, start, end int) string {
+	if start < 0 {
+		start = 0
+	}
+	for i := start; i < end && i < len(r); i++ {
+		r[i] = '*'
+	}
+
+	return string(r)
+}
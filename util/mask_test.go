@@ -0,0 +1,33 @@
+package util
+
+import "testing"
+
+func TestMask(t *testing.T) {
+	tests := []struct {
+		name  string
+		mask  func(string) string
+		input string
+		want  string
+	}{
+		{"mobile", MaskMobile, "13812341234", "138****1234"},
+		{"short mobile", MaskMobile, "12345", "***45"},
+		{"empty mobile", MaskMobile, "", ""},
+		{"email", MaskEmail, "alice@example.com", "a***@example.com"},
+		{"chinese email", MaskEmail, "张三@example.com", "张***@example.com"},
+		{"email without name", MaskEmail, "@example.com", "@***********"},
+		{"name", MaskName, "张三丰", "张**"},
+		{"single rune name", MaskName, "张", "*"},
+		{"empty name", MaskName, "", ""},
+		{"handle email", MaskHandle, "bob@example.com", "b***@example.com"},
+		{"handle mobile", MaskHandle, "13812341234", "138****1234"},
+		{"handle with mobile", MaskHandle, "user_13812341234", "user_138****1234"},
+		{"short handle", MaskHandle, "李四", "李*"},
+		{"handle", MaskHandle, "abcdef", "a****f"},
+		{"short digits kept", MaskHandle, "tom2024", "t*****4"},
+	}
+	for _, tt := range tests {
+		if got := tt.mask(tt.input); got != tt.want {
+			t.Errorf("%s: mask(%q) = %q, want %q", tt.name, tt.input, got, tt.want)
+		}
+	}
+}